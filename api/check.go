@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"text/template"
+)
+
+// Check describes a check the server runs itself on a schedule, with the
+// result used to update the box.
+type Check struct {
+	// Type of check, "http", "tcp", "exec" or "prometheus", "http" if not
+	// set.
+	Type     string    `json:"type,omitempty"`
+	Interval *Duration `json:"interval,omitempty"`
+	Timeout  *Duration `json:"timeout,omitempty"`
+
+	// The response (HTTP) or connection (TCP) time above which the box is
+	// amber or red.
+	AmberLatency *Duration `json:"amberLatency,omitempty"`
+	RedLatency   *Duration `json:"redLatency,omitempty"`
+
+	// HTTP checks, the box is red unless the response has ExpectStatus (any
+	// 2xx if not set) and the body matches BodyRegex (if set).
+	URL          string `json:"url,omitempty"`
+	Method       string `json:"method,omitempty"`
+	ExpectStatus int    `json:"expectStatus,omitempty"`
+	BodyRegex    string `json:"bodyRegex,omitempty"`
+
+	// TCP checks, the box is red unless a connection can be made to Address
+	// (host:port) and, if BannerRegex is set, what is read back (after
+	// sending Send if set) matches it.
+	Address     string `json:"address,omitempty"`
+	Send        string `json:"send,omitempty"`
+	BannerRegex string `json:"bannerRegex,omitempty"`
+
+	// Exec checks run Command (which is not passed to a shell) and use the
+	// Nagios plugin convention, exit code 0 is green, 1 amber, 2 red and
+	// anything else grey. The first line of output is the message, if
+	// Perfdata is set any performance data on it is added to the box info.
+	Command  []string `json:"command,omitempty"`
+	Perfdata bool     `json:"perfdata,omitempty"`
+
+	// Prometheus checks scrape URL and read Metric from the series with all
+	// of Labels, the box is red if it can't be. Otherwise Threshold sets the
+	// status, or the box's thresholds as the value is sent as a
+	// metric, green if there are none. Message is a text/template given the
+	// .Value and the first value of each of the .Metrics scraped.
+	Metric    string            `json:"metric,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Threshold *Threshold        `json:"threshold,omitempty"`
+	Message   string            `json:"message,omitempty"`
+}
+
+// Validate checks the check can be run.
+func (c *Check) Validate() error {
+	switch c.Type {
+	case "", "http":
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return fmt.Errorf("invalid check url: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid check url: %q", c.URL)
+		}
+		if c.BodyRegex != "" {
+			if _, err := regexp.Compile(c.BodyRegex); err != nil {
+				return fmt.Errorf("invalid check bodyRegex: %w", err)
+			}
+		}
+	case "tcp":
+		if _, _, err := net.SplitHostPort(c.Address); err != nil {
+			return fmt.Errorf("invalid check address: %w", err)
+		}
+		if c.BannerRegex != "" {
+			if _, err := regexp.Compile(c.BannerRegex); err != nil {
+				return fmt.Errorf("invalid check bannerRegex: %w", err)
+			}
+		}
+	case "exec":
+		if len(c.Command) == 0 || c.Command[0] == "" {
+			return fmt.Errorf("invalid check command: no command given")
+		}
+	case "prometheus":
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return fmt.Errorf("invalid check url: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid check url: %q", c.URL)
+		}
+		if c.Metric == "" {
+			return fmt.Errorf("invalid check metric: no metric given")
+		}
+		if c.Threshold != nil {
+			if err := c.Threshold.Validate(); err != nil {
+				return err
+			}
+			if c.Threshold.Metric != c.Metric {
+				return fmt.Errorf("invalid check threshold: it is for %s not %s", c.Threshold.Metric, c.Metric)
+			}
+		}
+		if c.Message != "" {
+			if _, err := template.New("message").Parse(c.Message); err != nil {
+				return fmt.Errorf("invalid check message: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid check type: %s", c.Type)
+	}
+
+	if c.AmberLatency != nil && c.RedLatency != nil && *c.RedLatency < *c.AmberLatency {
+		return fmt.Errorf("check redLatency must not be less than amberLatency")
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Dashboard is a view of some of the boxes, served at /d/{slug}.
+type Dashboard struct {
+	Slug  string `json:"slug"`
+	Title string `json:"title,omitempty"`
+	// The dashboard served at / instead of the list of dashboards.
+	Default bool `json:"default,omitempty"`
+
+	// Boxes shown are those listed in Boxes and those with all the labels in
+	// Selector, every box if neither is set.
+	Selector map[string]string `json:"selector,omitempty"`
+	Boxes    []string          `json:"boxes,omitempty"`
+
+	// Layout replaces the dashboard layout for this dashboard, if it has no
+	// groups the dashboard groups are used.
+	Layout *Layout `json:"layout,omitempty"`
+	Theme  string  `json:"theme,omitempty"`
+}
+
+// Validate checks the dashboard can be served.
+func (d *Dashboard) Validate() error {
+	if !groupIDRe.MatchString(d.Slug) {
+		return fmt.Errorf("invalid dashboard slug: %q", d.Slug)
+	}
+
+	if d.Theme != "" && !groupIDRe.MatchString(d.Theme) {
+		return fmt.Errorf("invalid dashboard theme: %q", d.Theme)
+	}
+
+	if d.Layout != nil {
+		if err := d.Layout.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Shows returns true if the box is shown on the dashboard.
+func (d *Dashboard) Shows(box Box) bool {
+	if len(d.Boxes) == 0 && len(d.Selector) == 0 {
+		return true
+	}
+
+	if slices.Contains(d.Boxes, box.ID) {
+		return true
+	}
+
+	if len(d.Selector) == 0 {
+		return false
+	}
+	for k, v := range d.Selector {
+		if value, ok := box.Labels[k]; !ok || value != v {
+			return false
+		}
+	}
+
+	return true
+}
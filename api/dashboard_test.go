@@ -0,0 +1,51 @@
+package api
+
+import "testing"
+
+func TestDashboardShows(t *testing.T) {
+	box := Box{ID: "web1", Labels: map[string]string{"team": "ops", "env": "prod"}}
+
+	tests := []struct {
+		name      string
+		dashboard Dashboard
+		expect    bool
+	}{
+		{name: "everything", dashboard: Dashboard{}, expect: true},
+		{name: "selector matches", dashboard: Dashboard{Selector: map[string]string{"team": "ops"}}, expect: true},
+		{name: "selector needs every label", dashboard: Dashboard{Selector: map[string]string{"team": "ops", "env": "dev"}}, expect: false},
+		{name: "selector label missing", dashboard: Dashboard{Selector: map[string]string{"owner": "ops"}}, expect: false},
+		{name: "listed", dashboard: Dashboard{Boxes: []string{"db1", "web1"}}, expect: true},
+		{name: "not listed", dashboard: Dashboard{Boxes: []string{"db1"}}, expect: false},
+		{name: "listed or selected", dashboard: Dashboard{Boxes: []string{"db1"}, Selector: map[string]string{"team": "ops"}}, expect: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dashboard.Shows(box); got != tt.expect {
+				t.Errorf("expected %v, got %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestDashboardValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		dashboard   Dashboard
+		expectError bool
+	}{
+		{name: "valid", dashboard: Dashboard{Slug: "noc", Title: "NOC wall"}},
+		{name: "missing slug", dashboard: Dashboard{Title: "NOC wall"}, expectError: true},
+		{name: "invalid slug", dashboard: Dashboard{Slug: "noc/wall"}, expectError: true},
+		{name: "invalid layout", dashboard: Dashboard{Slug: "noc", Layout: &Layout{Groups: []Group{{ID: "a"}, {ID: "a"}}}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.dashboard.Validate()
+			if (err != nil) != tt.expectError {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
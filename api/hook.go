@@ -0,0 +1,36 @@
+package api
+
+import (
+	"fmt"
+)
+
+// Hook turns JSON posted by another tool to /api/v1/hooks/{name} into an
+// event. ID, Status and Message are each a JSONPath starting with $, e.g.
+// $.build.status, a text/template such as {{.repo}}-ci, or plain text.
+//
+// The status found is looked up in StatusMap, with "*" matching anything not
+// listed, and otherwise must be the name of a status.
+type Hook struct {
+	Name      string            `json:"name"`
+	ID        string            `json:"id"`
+	Status    string            `json:"status"`
+	Message   string            `json:"message,omitempty"`
+	StatusMap map[string]Status `json:"statusMap,omitempty"`
+}
+
+// Validate checks the hook can be stored.
+func (h *Hook) Validate() error {
+	if !templateNameRe.MatchString(h.Name) {
+		return fmt.Errorf("invalid hook name: %q", h.Name)
+	}
+
+	if h.ID == "" {
+		return fmt.Errorf("invalid hook %s: no id given", h.Name)
+	}
+
+	if h.Status == "" {
+		return fmt.Errorf("invalid hook %s: no status given", h.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var groupIDRe = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Group is a section of the dashboard, shown with a heading, which boxes can
+// be placed in.
+type Group struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+}
+
+// Placement is where a box goes on the dashboard, boxes are shown in order
+// within their group with unordered boxes last.
+type Placement struct {
+	ID    string `json:"id"`
+	Group string `json:"group,omitempty"`
+	Order int    `json:"order,omitempty"`
+}
+
+// Layout is the arrangement of the dashboard. Boxes not in a group are shown
+// first, followed by the groups in order.
+type Layout struct {
+	Groups []Group     `json:"groups"`
+	Boxes  []Placement `json:"boxes,omitempty"`
+}
+
+// Validate checks the groups have usable and unique IDs.
+func (l *Layout) Validate() error {
+	seen := make(map[string]bool)
+	for _, g := range l.Groups {
+		if !groupIDRe.MatchString(g.ID) {
+			return fmt.Errorf("invalid group id: %q", g.ID)
+		}
+		if seen[g.ID] {
+			return fmt.Errorf("duplicate group id: %q", g.ID)
+		}
+		seen[g.ID] = true
+	}
+
+	return nil
+}
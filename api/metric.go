@@ -0,0 +1,239 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetricValue is a named number sent on an event, e.g. a queue depth.
+type MetricValue struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// MetricPoint is a value of a metric and when it was received.
+type MetricPoint struct {
+	Value float64   `json:"value"`
+	Time  time.Time `json:"time"`
+}
+
+// Metric is the recent history of a metric reported for a box.
+type Metric struct {
+	Name   string        `json:"name"`
+	Unit   string        `json:"unit,omitempty"`
+	Points []MetricPoint `json:"points"`
+}
+
+// Latest returns the most recent value of the metric.
+func (m *Metric) Latest() (float64, bool) {
+	if len(m.Points) == 0 {
+		return 0, false
+	}
+	return m.Points[len(m.Points)-1].Value, true
+}
+
+// Threshold derives a box's status from one of its metrics. The box is red or
+// amber while the metric is at or above Red or Amber (at or below with
+// Below), and green otherwise. A threshold can also be written as text, a
+// metric followed by conditions, e.g.
+//
+//	latency_ms > 500 => amber, > 2000 => red
+//
+// The worst status of the conditions which match is used, green if none do.
+type Threshold struct {
+	Metric string   `json:"metric"`
+	Amber  *float64 `json:"amber,omitempty"`
+	Red    *float64 `json:"red,omitempty"`
+	Below  bool     `json:"below,omitempty"`
+	// Conditions are set when the threshold is written as text.
+	Conditions []Condition `json:"-"`
+}
+
+// Condition is one comparison in a threshold.
+type Condition struct {
+	Op     string
+	Value  float64
+	Status Status
+}
+
+var conditionRe = regexp.MustCompile(`^\s*([A-Za-z_:][A-Za-z0-9_:.]*)?\s*(>=|<=|==|!=|>|<)\s*(\S+)\s*=>\s*(\w+)\s*$`)
+
+// ParseThreshold parses a threshold written as text.
+func ParseThreshold(s string) (Threshold, error) {
+	var t Threshold
+	for i, part := range strings.Split(s, ",") {
+		m := conditionRe.FindStringSubmatch(part)
+		if m == nil {
+			return t, fmt.Errorf("invalid threshold %q: can't parse %q", s, strings.TrimSpace(part))
+		}
+
+		switch {
+		case i == 0 && m[1] == "":
+			return t, fmt.Errorf("invalid threshold %q: no metric given", s)
+		case i == 0:
+			t.Metric = m[1]
+		case m[1] != "" && m[1] != t.Metric:
+			return t, fmt.Errorf("invalid threshold %q: only one metric can be used", s)
+		}
+
+		value, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			return t, fmt.Errorf("invalid threshold %q: %q is not a number", s, m[3])
+		}
+
+		var status Status
+		if err := status.UnmarshalJSON([]byte(strconv.Quote(m[4]))); err != nil {
+			return t, fmt.Errorf("invalid threshold %q: unknown status %q", s, m[4])
+		}
+		if status != Green && status != Amber && status != Red {
+			return t, fmt.Errorf("invalid threshold %q: only green, amber or red can be set", s)
+		}
+
+		t.Conditions = append(t.Conditions, Condition{Op: m[2], Value: value, Status: status})
+	}
+
+	return t, nil
+}
+
+// conditions returns the conditions the threshold is made of, those for Amber
+// and Red unless it was written as text.
+func (t *Threshold) conditions() []Condition {
+	if len(t.Conditions) > 0 {
+		return t.Conditions
+	}
+
+	op := ">="
+	if t.Below {
+		op = "<="
+	}
+
+	var conditions []Condition
+	if t.Amber != nil {
+		conditions = append(conditions, Condition{Op: op, Value: *t.Amber, Status: Amber})
+	}
+	if t.Red != nil {
+		conditions = append(conditions, Condition{Op: op, Value: *t.Red, Status: Red})
+	}
+	return conditions
+}
+
+// String returns the threshold written as text.
+func (t Threshold) String() string {
+	conditions := t.conditions()
+	parts := make([]string, 0, len(conditions))
+	for i, c := range conditions {
+		part := fmt.Sprintf("%s %g => %s", c.Op, c.Value, c.Status)
+		if i == 0 {
+			part = t.Metric + " " + part
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Validate checks the threshold can be applied.
+func (t *Threshold) Validate() error {
+	if t.Metric == "" {
+		return fmt.Errorf("invalid threshold: no metric given")
+	}
+
+	if len(t.Conditions) > 0 {
+		if t.Amber != nil || t.Red != nil || t.Below {
+			return fmt.Errorf("invalid threshold for %s: conditions can't be used with amber, red or below", t.Metric)
+		}
+		_, err := ParseThreshold(t.String())
+		return err
+	}
+
+	if t.Amber == nil && t.Red == nil {
+		return fmt.Errorf("invalid threshold for %s: no amber or red value given", t.Metric)
+	}
+
+	if t.Amber != nil && t.Red != nil {
+		if (!t.Below && *t.Red < *t.Amber) || (t.Below && *t.Red > *t.Amber) {
+			return fmt.Errorf("invalid threshold for %s: red is reached before amber", t.Metric)
+		}
+	}
+
+	return nil
+}
+
+func (c Condition) matches(value float64) bool {
+	switch c.Op {
+	case ">":
+		return value > c.Value
+	case ">=":
+		return value >= c.Value
+	case "<":
+		return value < c.Value
+	case "<=":
+		return value <= c.Value
+	case "==":
+		return value == c.Value
+	case "!=":
+		return value != c.Value
+	}
+	return false
+}
+
+// Status returns the status for a value of the metric, and a message saying
+// which condition set it.
+func (t *Threshold) Status(value float64) (Status, string) {
+	status := Green
+	var message string
+	for _, c := range t.conditions() {
+		if c.matches(value) && c.Status.Worse(status) {
+			status = c.Status
+			message = fmt.Sprintf("%s %g %s %g", t.Metric, value, c.Op, c.Value)
+		}
+	}
+
+	return status, message
+}
+
+// MarshalJSON writes a threshold as text if it was written as text.
+func (t Threshold) MarshalJSON() ([]byte, error) {
+	if len(t.Conditions) > 0 {
+		return json.Marshal(t.String())
+	}
+
+	type plain Threshold
+	return json.Marshal(plain(t))
+}
+
+// UnmarshalJSON reads a threshold from an object or from text.
+func (t *Threshold) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		parsed, err := ParseThreshold(s)
+		if err != nil {
+			return err
+		}
+		*t = parsed
+		return nil
+	}
+
+	type plain Threshold
+	return json.Unmarshal(b, (*plain)(t))
+}
+
+// Validate checks the parts of an event which can't be checked by
+// unmarshalling.
+func (e *Event) Validate() error {
+	for _, m := range e.Metrics {
+		if m.Name == "" {
+			return fmt.Errorf("invalid metric: no name given")
+		}
+		if math.IsNaN(m.Value) || math.IsInf(m.Value, 0) {
+			return fmt.Errorf("invalid metric %s: value is not a number", m.Name)
+		}
+	}
+
+	return nil
+}
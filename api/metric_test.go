@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func ptrFloat(f float64) *float64 {
+	return &f
+}
+
+func TestThresholdValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		threshold   Threshold
+		expectError bool
+	}{
+		{name: "amber and red", threshold: Threshold{Metric: "queue", Amber: ptrFloat(100), Red: ptrFloat(1000)}},
+		{name: "red only", threshold: Threshold{Metric: "queue", Red: ptrFloat(1000)}},
+		{name: "below", threshold: Threshold{Metric: "free", Amber: ptrFloat(20), Red: ptrFloat(5), Below: true}},
+		{name: "no metric", threshold: Threshold{Red: ptrFloat(1)}, expectError: true},
+		{name: "no limits", threshold: Threshold{Metric: "queue"}, expectError: true},
+		{name: "red before amber", threshold: Threshold{Metric: "queue", Amber: ptrFloat(1000), Red: ptrFloat(100)}, expectError: true},
+		{name: "red before amber below", threshold: Threshold{Metric: "free", Amber: ptrFloat(5), Red: ptrFloat(20), Below: true}, expectError: true},
+		{name: "conditions", threshold: Threshold{Metric: "latency_ms", Conditions: []Condition{{Op: ">", Value: 500, Status: Amber}}}},
+		{name: "conditions with limits", threshold: Threshold{Metric: "latency_ms", Red: ptrFloat(1), Conditions: []Condition{{Op: ">", Value: 500, Status: Amber}}}, expectError: true},
+		{name: "condition setting grey", threshold: Threshold{Metric: "latency_ms", Conditions: []Condition{{Op: ">", Value: 500, Status: Grey}}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.threshold.Validate()
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestThresholdStatus(t *testing.T) {
+	above := Threshold{Metric: "queue", Amber: ptrFloat(100), Red: ptrFloat(1000)}
+	below := Threshold{Metric: "free", Amber: ptrFloat(20), Red: ptrFloat(5), Below: true}
+	conditions, err := ParseThreshold("latency_ms > 500 => amber, > 2000 => red")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		threshold Threshold
+		value     float64
+		status    Status
+		message   string
+	}{
+		{above, 99, Green, ""},
+		{above, 100, Amber, "queue 100 >= 100"},
+		{above, 1234, Red, "queue 1234 >= 1000"},
+		{below, 50, Green, ""},
+		{below, 12.5, Amber, "free 12.5 <= 20"},
+		{below, 5, Red, "free 5 <= 5"},
+		{conditions, 500, Green, ""},
+		{conditions, 501, Amber, "latency_ms 501 > 500"},
+		{conditions, 2500, Red, "latency_ms 2500 > 2000"},
+	}
+
+	for _, tt := range tests {
+		status, message := tt.threshold.Status(tt.value)
+		if status != tt.status || message != tt.message {
+			t.Errorf("%s %g: expected %s %q, got %s %q", tt.threshold.Metric, tt.value, tt.status, tt.message, status, message)
+		}
+	}
+}
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		threshold   string
+		expect      string
+		expectError bool
+	}{
+		{threshold: "latency_ms > 500 => amber, > 2000 => red", expect: "latency_ms > 500 => amber, > 2000 => red"},
+		{threshold: "latency_ms>500=>amber,latency_ms>2000=>red", expect: "latency_ms > 500 => amber, > 2000 => red"},
+		{threshold: "free_gb <= 10 => amber, <= 2.5 => red", expect: "free_gb <= 10 => amber, <= 2.5 => red"},
+		{threshold: "healthy != 1 => red", expect: "healthy != 1 => red"},
+		{threshold: "> 500 => amber", expectError: true},
+		{threshold: "latency_ms > 500 => amber, errors > 1 => red", expectError: true},
+		{threshold: "latency_ms > fast => amber", expectError: true},
+		{threshold: "latency_ms > 500 => purple", expectError: true},
+		{threshold: "latency_ms > 500 => noUpdate", expectError: true},
+		{threshold: "latency_ms ~ 500 => red", expectError: true},
+		{threshold: "", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.threshold, func(t *testing.T) {
+			th, err := ParseThreshold(tt.threshold)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got %+v", th)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if th.String() != tt.expect {
+				t.Errorf("expected %q, got %q", tt.expect, th.String())
+			}
+		})
+	}
+}
+
+func TestThresholdJSON(t *testing.T) {
+	var box Box
+	if err := json.Unmarshal([]byte(`{"name": "api", "thresholds": ["latency_ms > 500 => amber, > 2000 => red", {"metric": "queue", "red": 100}]}`), &box); err != nil {
+		t.Fatal(err)
+	}
+	if len(box.Thresholds) != 2 || box.Thresholds[0].Metric != "latency_ms" || len(box.Thresholds[0].Conditions) != 2 || box.Thresholds[1].Red == nil {
+		t.Fatalf("expected both forms to be parsed, got %+v", box.Thresholds)
+	}
+
+	b, err := json.Marshal(box.Thresholds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `["latency_ms \u003e 500 =\u003e amber, \u003e 2000 =\u003e red",{"metric":"queue","red":100}]` {
+		t.Errorf("expected each threshold to be written as it was read, got %s", b)
+	}
+
+	if err := json.Unmarshal([]byte(`{"thresholds": ["latency_ms > lots => red"]}`), &box); err == nil {
+		t.Error("expected an invalid threshold to fail to unmarshal")
+	}
+}
+
+func TestEventValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		event       Event
+		expectError bool
+	}{
+		{name: "no metrics", event: Event{Status: Green}},
+		{name: "metrics", event: Event{Metrics: []MetricValue{{Name: "queue", Value: 12, Unit: "jobs"}}}},
+		{name: "no name", event: Event{Metrics: []MetricValue{{Value: 12}}}, expectError: true},
+		{name: "not a number", event: Event{Metrics: []MetricValue{{Name: "queue", Value: math.NaN()}}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.event.Validate()
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
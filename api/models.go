@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"sort"
 	"strconv"
 	"time"
 )
@@ -29,14 +31,37 @@ func (d *Duration) Duration() time.Duration {
 
 // Event struct is used to stream events to dashboard.
 type Event struct {
-	ID          string    `json:"id,omitempty"`
-	After       string    `json:"after,omitempty"`
-	Box         *Box      `json:"box,omitempty"`
-	Status      Status    `json:"status,omitempty"`
-	Message     string    `json:"lastMessage,omitempty"`
-	ExpireAfter *Duration `json:"expireAfter"`
-	MaxTBU      *Duration `json:"maxTBU"`
-	Type        string    `json:"type"`
+	ID             string            `json:"id,omitempty"`
+	After          string            `json:"after,omitempty"`
+	Box            *Box              `json:"box,omitempty"`
+	Status         Status            `json:"status,omitempty"`
+	PreviousStatus *Status           `json:"previousStatus,omitempty"`
+	Quiet          bool              `json:"quiet,omitempty"`
+	Message        string            `json:"lastMessage,omitempty"`
+	Info           map[string]string `json:"info,omitempty"`
+	Metrics        []MetricValue     `json:"metrics,omitempty"`
+	ExpireAfter    *Duration         `json:"expireAfter"`
+	MaxTBU         *Duration         `json:"maxTBU"`
+	Type           string            `json:"type"`
+}
+
+// Event types sent on the event stream (/api/v1/stream).
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+	// Sent instead of EventUpdated when the update changed the box status.
+	EventStatusChanged = "statusChanged"
+	// Sent when events have been missed, the box list should be fetched
+	// again.
+	EventResync    = "resync"
+	EventKeepalive = "keepalive"
+)
+
+// StatusChange records when a box changed to a status.
+type StatusChange struct {
+	Status Status    `json:"status"`
+	Time   time.Time `json:"time"`
 }
 
 // Links describes a URL with a name.
@@ -101,6 +126,26 @@ func (s Status) MarshalJSON() ([]byte, error) {
 	return json.Marshal(s.String())
 }
 
+// severity ranks the statuses, worst last. A box which has missed an update
+// is worse than an amber one but not as bad as a red one.
+var severity = map[Status]int{Green: 0, Grey: 1, Amber: 2, NoUpdate: 3, Red: 4}
+
+// Worse reports whether s is worse than other.
+func (s Status) Worse(other Status) bool {
+	return severity[s] > severity[other]
+}
+
+// StatusesByWorst returns every status, worst first.
+func StatusesByWorst() []Status {
+	statuses := make([]Status, 0, len(severity))
+	for s := range severity {
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Worse(statuses[j]) })
+
+	return statuses
+}
+
 type BoxSize int
 
 const (
@@ -221,20 +266,37 @@ func (d Duration) MarshalJSON() (b []byte, err error) {
 
 // Box represents a single item on our monitoring screen.
 type Box struct {
-	ID          string             `json:"id"`
-	Description string             `json:"description,omitempty"`
-	DisplayName string             `json:"displayName,omitempty"`
-	Name        string             `json:"name"`
-	Info        *map[string]string `json:"info,omitempty"`
-	Parent      string             `json:"parent,omitempty"`
-	Size        BoxSize            `json:"size"`
-	Status      Status             `json:"status"`
-	ExpireAfter *Duration          `json:"expireAfter,omitempty"`
-	MaxTBU      *Duration          `json:"maxTBU,omitempty"`
-	Messages    []Message          `json:"messages"`
-	LastUpdate  time.Time          `json:"lastUpdate"`
-	LastMessage string             `json:"lastMessage"`
-	Links       []Links            `json:"links"`
+	ID               string             `json:"id"`
+	Description      string             `json:"description,omitempty"`
+	DisplayName      string             `json:"displayName,omitempty"`
+	Name             string             `json:"name"`
+	Info             *map[string]string `json:"info,omitempty"`
+	Labels           map[string]string  `json:"labels,omitempty"`
+	Parent           string             `json:"parent,omitempty"`
+	Size             BoxSize            `json:"size"`
+	Group            string             `json:"group,omitempty"`
+	Order            int                `json:"order,omitempty"`
+	Status           Status             `json:"status"`
+	Acknowledged     bool               `json:"acknowledged,omitempty"`
+	Quiet            bool               `json:"quiet,omitempty"`
+	ExpireAfter      *Duration          `json:"expireAfter,omitempty"`
+	MaxTBU           *Duration          `json:"maxTBU,omitempty"`
+	Schedule         *Schedule          `json:"schedule,omitempty"`
+	Grace            *Duration          `json:"grace,omitempty"`
+	ExpectedDuration *Duration          `json:"expectedDuration,omitempty"`
+	RunStarted       *time.Time         `json:"runStarted,omitempty"`
+	LastDuration     *Duration          `json:"lastDuration,omitempty"`
+	Messages         []Message          `json:"messages"`
+	History          []StatusChange     `json:"history,omitempty"`
+	Metrics          []Metric           `json:"metrics,omitempty"`
+	Thresholds       []Threshold        `json:"thresholds,omitempty"`
+	LastUpdate       time.Time          `json:"lastUpdate"`
+	LastMessage      string             `json:"lastMessage"`
+	Links            []Links            `json:"links"`
+	Runbook          string             `json:"runbook,omitempty"`
+	Check            *Check             `json:"check,omitempty"`
+	Template         string             `json:"template,omitempty"`
+	TemplateVars     map[string]string  `json:"templateVars,omitempty"`
 }
 
 func (b *Box) Sanitise() {
@@ -244,6 +306,42 @@ func (b *Box) Sanitise() {
 	if b.ExpireAfter != nil && *b.ExpireAfter == 0 {
 		b.ExpireAfter = nil
 	}
+	if b.ExpectedDuration != nil && *b.ExpectedDuration == 0 {
+		b.ExpectedDuration = nil
+	}
+}
+
+// Validate checks the parts of a box which can't be checked by unmarshalling.
+func (b *Box) Validate() error {
+	if b.Runbook != "" {
+		u, err := url.Parse(b.Runbook)
+		if err != nil {
+			return fmt.Errorf("invalid runbook url: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid runbook url: %q", b.Runbook)
+		}
+	}
+
+	if b.Schedule != nil {
+		if err := b.Schedule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if b.Check != nil {
+		if err := b.Check.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range b.Thresholds {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (b *Box) UnmarshalJSON(data []byte) error {
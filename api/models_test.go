@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -576,3 +577,60 @@ func compareDurationPtr(got, want *Duration) bool {
 	}
 	return false
 }
+
+func TestBoxValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		box         Box
+		expectError bool
+	}{
+		{name: "no check", box: Box{Name: "test"}},
+		{name: "http check", box: Box{Check: &Check{URL: "https://example.com/health", BodyRegex: "ok"}}},
+		{name: "explicit http type", box: Box{Check: &Check{Type: "http", URL: "http://example.com"}}},
+		{name: "missing url", box: Box{Check: &Check{}}, expectError: true},
+		{name: "invalid scheme", box: Box{Check: &Check{URL: "ftp://example.com"}}, expectError: true},
+		{name: "invalid regex", box: Box{Check: &Check{URL: "http://example.com", BodyRegex: "("}}, expectError: true},
+		{name: "unknown type", box: Box{Check: &Check{Type: "carrier-pigeon"}}, expectError: true},
+		{name: "tcp check", box: Box{Check: &Check{Type: "tcp", Address: "db:5432", BannerRegex: "^220"}}},
+		{name: "tcp missing port", box: Box{Check: &Check{Type: "tcp", Address: "db"}}, expectError: true},
+		{name: "tcp invalid regex", box: Box{Check: &Check{Type: "tcp", Address: "db:25", BannerRegex: "["}}, expectError: true},
+		{name: "exec check", box: Box{Check: &Check{Type: "exec", Command: []string{"check_disk", "-w", "10%"}}}},
+		{name: "exec missing command", box: Box{Check: &Check{Type: "exec"}}, expectError: true},
+		{name: "runbook", box: Box{Runbook: "https://wiki.example.com/runbooks/db"}},
+		{name: "runbook not a url", box: Box{Runbook: "restart it"}, expectError: true},
+		{name: "runbook javascript url", box: Box{Runbook: "javascript:alert(1)"}, expectError: true},
+		{name: "prometheus check", box: Box{Check: &Check{Type: "prometheus", URL: "http://mc:9090/metrics", Metric: "up", Message: "{{ .Value }} up"}}},
+		{name: "prometheus missing metric", box: Box{Check: &Check{Type: "prometheus", URL: "http://mc:9090/metrics"}}, expectError: true},
+		{name: "prometheus invalid url", box: Box{Check: &Check{Type: "prometheus", URL: "mc:9090", Metric: "up"}}, expectError: true},
+		{name: "prometheus threshold for another metric", box: Box{Check: &Check{Type: "prometheus", URL: "http://mc:9090/metrics", Metric: "up", Threshold: &Threshold{Metric: "down", Conditions: []Condition{{Op: "<", Value: 1, Status: Red}}}}}, expectError: true},
+		{name: "prometheus invalid message", box: Box{Check: &Check{Type: "prometheus", URL: "http://mc:9090/metrics", Metric: "up", Message: "{{ .Value"}}, expectError: true},
+		{name: "red latency below amber", box: Box{Check: &Check{Type: "tcp", Address: "db:5432", AmberLatency: ptrDuration(time.Second), RedLatency: ptrDuration(time.Millisecond)}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.box.Validate()
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func ptrDuration(d time.Duration) *Duration {
+	v := Duration(d)
+	return &v
+}
+
+func TestStatusesByWorst(t *testing.T) {
+	expect := []Status{Red, NoUpdate, Amber, Grey, Green}
+	if got := StatusesByWorst(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected %v, got %v", expect, got)
+	}
+	if !Amber.Worse(Green) || Green.Worse(Amber) || Red.Worse(Red) {
+		t.Error("expected amber to be worse than green and no status to be worse than itself")
+	}
+}
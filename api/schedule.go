@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule is when a box is expected to be updated, for jobs which don't run
+// at a fixed interval.
+type Schedule struct {
+	// A standard five field cron expression ("0 2 * * 1-5") or a descriptor
+	// such as "@daily".
+	Cron string `json:"cron"`
+	// IANA time zone the expression is in, the server's local time if not
+	// set.
+	Timezone string `json:"timezone,omitempty"`
+
+	// The parsed expression and time zone, set when unmarshalled so they
+	// aren't parsed every time the schedule is checked.
+	schedule cron.Schedule
+	location *time.Location
+}
+
+func (s *Schedule) UnmarshalJSON(b []byte) error {
+	type plain Schedule
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	*s = Schedule{Cron: p.Cron, Timezone: p.Timezone}
+
+	// Invalid schedules are reported by Validate.
+	if schedule, loc, err := s.parse(); err == nil {
+		s.schedule, s.location = schedule, loc
+	}
+
+	return nil
+}
+
+func (s *Schedule) parse() (cron.Schedule, *time.Location, error) {
+	schedule, err := cron.ParseStandard(s.Cron)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid schedule cron: %w", err)
+	}
+
+	loc := time.Local
+	if s.Timezone != "" {
+		if loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return nil, nil, fmt.Errorf("invalid schedule timezone: %w", err)
+		}
+	}
+
+	return schedule, loc, nil
+}
+
+// Validate checks the cron expression and time zone can be used.
+func (s *Schedule) Validate() error {
+	_, _, err := s.parse()
+	return err
+}
+
+// Next returns the first time the schedule is due after t, or the zero time
+// if the schedule is invalid or never due.
+func (s *Schedule) Next(t time.Time) time.Time {
+	schedule, loc := s.schedule, s.location
+	if schedule == nil {
+		var err error
+		if schedule, loc, err = s.parse(); err != nil {
+			return time.Time{}
+		}
+	}
+
+	return schedule.Next(t.In(loc))
+}
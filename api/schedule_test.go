@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		after    time.Time
+		expect   time.Time
+	}{
+		{
+			name:     "daily at 02:00",
+			schedule: Schedule{Cron: "0 2 * * *", Timezone: "UTC"},
+			after:    time.Date(2026, 3, 2, 2, 5, 0, 0, time.UTC),
+			expect:   time.Date(2026, 3, 3, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekdays skips the weekend",
+			schedule: Schedule{Cron: "0 2 * * 1-5", Timezone: "UTC"},
+			after:    time.Date(2026, 3, 6, 2, 5, 0, 0, time.UTC), // Friday
+			expect:   time.Date(2026, 3, 9, 2, 0, 0, 0, time.UTC), // Monday
+		},
+		{
+			name:     "in a time zone",
+			schedule: Schedule{Cron: "0 2 * * *", Timezone: "Europe/London"},
+			after:    time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC),
+			expect:   time.Date(2026, 7, 2, 2, 0, 0, 0, london),
+		},
+		{
+			name:     "descriptor",
+			schedule: Schedule{Cron: "@hourly", Timezone: "UTC"},
+			after:    time.Date(2026, 3, 2, 2, 5, 0, 0, time.UTC),
+			expect:   time.Date(2026, 3, 2, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "invalid",
+			schedule: Schedule{Cron: "every tuesday"},
+			after:    time.Date(2026, 3, 2, 2, 5, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.Next(tt.after); !got.Equal(tt.expect) {
+				t.Errorf("expected %s, got %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		schedule    Schedule
+		expectError bool
+	}{
+		{name: "valid", schedule: Schedule{Cron: "30 6 * * 1-5", Timezone: "UTC"}},
+		{name: "no time zone", schedule: Schedule{Cron: "@daily"}},
+		{name: "invalid cron", schedule: Schedule{Cron: "* * *"}, expectError: true},
+		{name: "invalid time zone", schedule: Schedule{Cron: "@daily", Timezone: "Mars/Olympus_Mons"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schedule.Validate()
+			if (err != nil) != tt.expectError {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestScheduleUnmarshalJSON(t *testing.T) {
+	var s Schedule
+	if err := json.Unmarshal([]byte(`{"cron": "0 2 * * *", "timezone": "UTC"}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.schedule == nil || s.location == nil {
+		t.Fatal("expected the schedule to be parsed when unmarshalled")
+	}
+
+	after := time.Date(2026, 3, 2, 2, 5, 0, 0, time.UTC)
+	if got, expect := s.Next(after), time.Date(2026, 3, 3, 2, 0, 0, 0, time.UTC); !got.Equal(expect) {
+		t.Errorf("expected %s, got %s", expect, got)
+	}
+
+	// Invalid schedules still unmarshal, Validate reports them.
+	var invalid Schedule
+	if err := json.Unmarshal([]byte(`{"cron": "every tuesday"}`), &invalid); err != nil {
+		t.Fatal(err)
+	}
+	if invalid.Validate() == nil || !invalid.Next(after).IsZero() {
+		t.Errorf("expected an invalid schedule, got %+v", invalid)
+	}
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// SyslogRule updates a box from syslog messages which match Pattern, and
+// come from a host matching Host if it is set. ID and Message can use $1 or
+// ${name} for the pattern's groups, and ${host}, ${app} and ${message} for
+// the parts of the syslog message. Status must be given and Message defaults
+// to the syslog message.
+type SyslogRule struct {
+	Host    string  `json:"host,omitempty"`
+	Pattern string  `json:"pattern"`
+	ID      string  `json:"id"`
+	Status  *Status `json:"status"`
+	Message string  `json:"message,omitempty"`
+}
+
+// Validate checks the rule can be applied.
+func (r *SyslogRule) Validate() error {
+	if r.ID == "" {
+		return fmt.Errorf("invalid syslog rule %q: no id given", r.Pattern)
+	}
+
+	if r.Status == nil {
+		return fmt.Errorf("invalid syslog rule %q: no status given", r.Pattern)
+	}
+
+	if _, err := regexp.Compile(r.Pattern); err != nil {
+		return fmt.Errorf("invalid syslog rule pattern: %w", err)
+	}
+
+	if _, err := regexp.Compile(r.Host); err != nil {
+		return fmt.Errorf("invalid syslog rule host: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var templateNameRe = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Template is a box skeleton used to create boxes which differ only by a few
+// values. Any string in the box can use text/template placeholders such as
+// {{.host}} which are filled in from the variables given when it is
+// instantiated.
+type Template struct {
+	Name string `json:"name"`
+	Box  Box    `json:"box"`
+}
+
+// Validate checks the template can be stored.
+func (t *Template) Validate() error {
+	if !templateNameRe.MatchString(t.Name) {
+		return fmt.Errorf("invalid template name: %q", t.Name)
+	}
+
+	return nil
+}
+
+// TemplateInstance is a request to create a box from a template.
+type TemplateInstance struct {
+	// ID of the box to create, used if the template doesn't set one, a
+	// random ID if neither do.
+	ID   string            `json:"id,omitempty"`
+	Vars map[string]string `json:"vars"`
+}
@@ -1,10 +1,15 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/baelish/alive/api"
 )
@@ -448,3 +453,90 @@ func TestCreateEvent(t *testing.T) {
 		})
 	}
 }
+
+func TestSubscribe(t *testing.T) {
+	t.Run("receives events and resumes after reconnecting", func(t *testing.T) {
+		connections := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/stream" {
+				t.Errorf("expected path /api/v1/stream, got %s", r.URL.Path)
+			}
+			if r.URL.Query().Get("type") != api.EventStatusChanged {
+				t.Errorf("expected type filter %q, got %q", api.EventStatusChanged, r.URL.Query().Get("type"))
+			}
+
+			connections++
+			w.Header().Set("Content-Type", "text/event-stream")
+			switch connections {
+			case 1:
+				if id := r.Header.Get("Last-Event-ID"); id != "" {
+					t.Errorf("expected no Last-Event-ID on first connection, got %q", id)
+				}
+				fmt.Fprint(w, "data: {\"type\": \"keepalive\"}\n\n")
+				fmt.Fprint(w, "id: 1\ndata: {\"type\": \"statusChanged\", \"id\": \"box1\", \"status\": \"red\"}\n\n")
+				// Returning drops the connection.
+			default:
+				if id := r.Header.Get("Last-Event-ID"); id != "1" {
+					t.Errorf("expected Last-Event-ID 1 after reconnecting, got %q", id)
+				}
+				fmt.Fprint(w, "id: 2\ndata: {\"type\": \"statusChanged\", \"id\": \"box1\", \"status\": \"green\"}\n\n")
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			}
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		client := NewClient(server.URL)
+		events, err := client.Subscribe(ctx, SubscribeOptions{Types: []string{api.EventStatusChanged}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, expected := range []api.Status{api.Red, api.Green} {
+			select {
+			case event := <-events:
+				if event.ID != "box1" || event.Status != expected {
+					t.Errorf("expected box1 %s, got %s %s", expected, event.ID, event.Status)
+				}
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for %s event", expected)
+			}
+		}
+
+		cancel()
+		for range events {
+		}
+	})
+
+	t.Run("joins events sent on several data lines", func(t *testing.T) {
+		body := io.NopCloser(strings.NewReader("id: 7\ndata: {\"type\": \"statusChanged\", \"id\": \"box1\",\ndata: \"status\": \"red\", \"lastMessage\": \"disk full\"}\n\n"))
+		events := make(chan api.Event, 1)
+
+		if id := readStream(context.Background(), body, "", events); id != "7" {
+			t.Errorf("expected last event ID 7, got %q", id)
+		}
+		select {
+		case event := <-events:
+			if event.ID != "box1" || event.Status != api.Red || event.Message != "disk full" {
+				t.Errorf("expected box1 red with its message, got %+v", event)
+			}
+		default:
+			t.Fatal("expected an event")
+		}
+	})
+
+	t.Run("returns an error if the stream can not be opened", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL)
+		if _, err := client.Subscribe(context.Background(), SubscribeOptions{}); err == nil {
+			t.Error("expected error but got none")
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/baelish/alive/api"
+)
+
+const (
+	minReconnectDelay = 1 * time.Second
+	maxReconnectDelay = 30 * time.Second
+
+	// The server sends keepalives every few seconds, if nothing arrives for
+	// this long the connection is assumed to be dead.
+	streamIdleTimeout = 30 * time.Second
+)
+
+// SubscribeOptions filters the events received by Subscribe, empty fields
+// match everything.
+type SubscribeOptions struct {
+	Types    []string
+	IDs      []string
+	Statuses []api.Status
+}
+
+func (o SubscribeOptions) query() string {
+	q := url.Values{}
+	for _, t := range o.Types {
+		q.Add("type", t)
+	}
+	for _, id := range o.IDs {
+		q.Add("id", id)
+	}
+	for _, s := range o.Statuses {
+		q.Add("status", s.String())
+	}
+
+	return q.Encode()
+}
+
+// Subscribe streams box events from the server until ctx is cancelled, at
+// which point the returned channel is closed. If the connection drops it
+// reconnects and resumes where it left off, an event of type api.EventResync
+// means events were missed and the boxes should be fetched again.
+func (c *Client) Subscribe(ctx context.Context, opts SubscribeOptions) (<-chan api.Event, error) {
+	url := fmt.Sprintf("%s/api/v1/stream?%s", c.baseURL, opts.query())
+
+	// The stream is long lived so must not use the client timeout.
+	httpClient := &http.Client{Transport: c.httpClient.Transport}
+
+	resp, err := openStream(ctx, httpClient, url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan api.Event)
+	go func() {
+		defer close(events)
+
+		var lastEventID string
+		delay := minReconnectDelay
+		for {
+			if resp != nil {
+				lastEventID = readStream(ctx, resp.Body, lastEventID, events)
+				resp.Body.Close()
+				delay = minReconnectDelay
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			resp, err = openStream(ctx, httpClient, url, lastEventID)
+			if err != nil {
+				resp = nil
+				delay = min(delay*2, maxReconnectDelay)
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func openStream(ctx context.Context, httpClient *http.Client, url, lastEventID string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// readStream reads server-sent events from body until it ends, sending each
+// to events. It returns the ID of the last event read.
+func readStream(ctx context.Context, body io.ReadCloser, lastEventID string, events chan<- api.Event) string {
+	idle := time.AfterFunc(streamIdleTimeout, func() { body.Close() })
+	defer idle.Stop()
+
+	var id string
+	var data []string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		idle.Reset(streamIdleTimeout)
+
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id:"):
+			id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+			continue
+		case strings.HasPrefix(line, "data:"):
+			// Several data lines are joined by new lines.
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		case line != "":
+			continue
+		}
+
+		// A blank line ends the event.
+		if id != "" {
+			lastEventID = id
+		}
+
+		var event api.Event
+		if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event); err == nil && event.Type != api.EventKeepalive {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return lastEventID
+			}
+		}
+		id, data = "", nil
+	}
+
+	return lastEventID
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/baelish/alive/api"
+)
+
+// alertmanagerPayload is the part of an Alertmanager webhook we use.
+type alertmanagerPayload struct {
+	Alerts []alertmanagerAlert `json:"alerts"`
+}
+
+type alertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	GeneratorURL string            `json:"generatorURL"`
+}
+
+// alertmanagerConfig is set by query parameters on the webhook url, so each
+// Alertmanager receiver can map its alerts differently.
+type alertmanagerConfig struct {
+	// Labels whose values make up the box ID.
+	Labels []string
+	// Template used to create boxes which don't exist yet.
+	Template string
+	// Severities which make a firing alert amber rather than red.
+	Amber []string
+}
+
+var (
+	defaultAlertmanagerLabels = []string{"alertname", "instance"}
+	defaultAlertmanagerAmber  = []string{"warning", "warn"}
+)
+
+// Characters which can't be used in a box ID, it is used in urls and as an
+// element ID on the dashboard.
+var invalidIDChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+func alertmanagerConfigFromQuery(q url.Values) alertmanagerConfig {
+	list := func(name string, def []string) []string {
+		if !q.Has(name) {
+			return def
+		}
+		var values []string
+		for _, v := range q[name] {
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					values = append(values, s)
+				}
+			}
+		}
+		return values
+	}
+
+	return alertmanagerConfig{
+		Labels:   list("labels", defaultAlertmanagerLabels),
+		Template: q.Get("template"),
+		Amber:    list("amber", defaultAlertmanagerAmber),
+	}
+}
+
+// apiAlertmanager receives Alertmanager webhooks, updating a box for each
+// alert and creating it if needed.
+func apiAlertmanager(w http.ResponseWriter, r *http.Request) {
+	config := alertmanagerConfigFromQuery(r.URL.Query())
+	if len(config.Labels) == 0 {
+		handleApiErrorResponse(w, http.StatusBadRequest, nil, "no labels given to make box IDs from", false, true)
+		return
+	}
+
+	var t *api.Template
+	if config.Template != "" {
+		found, ok := templateStore.Get(config.Template)
+		if !ok {
+			handleApiErrorResponse(w, http.StatusBadRequest, nil, "template not found", false, true)
+			return
+		}
+		t = &found
+	}
+
+	var payload alertmanagerPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, true)
+		return
+	}
+
+	updated := make([]api.Event, 0, len(payload.Alerts))
+	var errs []error
+	for _, alert := range payload.Alerts {
+		event, err := applyAlert(alert, config, t)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		updated = append(updated, event)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		msg := fmt.Sprintf("updated boxes for %d of %d alerts", len(updated), len(payload.Alerts))
+		handleApiErrorResponse(w, http.StatusBadRequest, err, msg, true, true)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+// applyAlert updates the box for an alert, creating it first if it doesn't
+// exist.
+func applyAlert(alert alertmanagerAlert, config alertmanagerConfig, t *api.Template) (api.Event, error) {
+	id := alertBoxID(alert.Labels, config.Labels)
+	if id == "" {
+		return api.Event{}, fmt.Errorf("alert has none of the labels %s", strings.Join(config.Labels, ", "))
+	}
+
+	if !boxStore.Exists(id) {
+		box, err := alertBox(id, alert, config, t)
+		if err != nil {
+			return api.Event{}, fmt.Errorf("could not create box %s: %w", id, err)
+		}
+		// Another webhook may have created it in the meantime.
+		if _, err := addBox(box); err != nil && !boxStore.Exists(id) {
+			return api.Event{}, err
+		}
+	}
+
+	event := api.Event{
+		ID:      id,
+		Status:  alertStatus(alert, config.Amber),
+		Message: alertMessage(alert),
+	}
+
+	return event, update(event)
+}
+
+// alertBoxID joins the values of the labels the alert has into a box ID.
+func alertBoxID(labels map[string]string, names []string) string {
+	var parts []string
+	for _, name := range names {
+		if v := labels[name]; v != "" {
+			parts = append(parts, v)
+		}
+	}
+
+	return strings.Trim(invalidIDChars.ReplaceAllString(strings.Join(parts, "-"), "-"), "-")
+}
+
+// alertBox returns the box to create for an alert, from the template if one
+// is given. The template's variables are the alert's labels, the box always
+// gets the ID made from them so later alerts find it.
+func alertBox(id string, alert alertmanagerAlert, config alertmanagerConfig, t *api.Template) (api.Box, error) {
+	if t != nil {
+		box, err := renderTemplate(*t, api.TemplateInstance{ID: id, Vars: alert.Labels})
+		box.ID = id
+		return box, err
+	}
+
+	var name []string
+	for _, label := range config.Labels {
+		if v := alert.Labels[label]; v != "" {
+			name = append(name, v)
+		}
+	}
+
+	box := api.Box{
+		ID:          id,
+		Name:        strings.Join(name, " "),
+		Description: alert.Annotations["description"],
+		Size:        api.Small,
+		Status:      api.Grey,
+		Labels:      alert.Labels,
+	}
+	if alert.GeneratorURL != "" {
+		box.Links = []api.Links{{Name: "Source", URL: alert.GeneratorURL}}
+	}
+	box.Runbook = alert.Annotations["runbook_url"]
+	if box.Validate() != nil {
+		// Don't lose the alert over a bad runbook annotation.
+		box.Runbook = ""
+	}
+
+	return box, validateBox(box)
+}
+
+// alertStatus is green for resolved alerts, amber for firing alerts with one
+// of the amber severities and red for the rest.
+func alertStatus(alert alertmanagerAlert, amber []string) api.Status {
+	if alert.Status == "resolved" {
+		return api.Green
+	}
+	severity := alert.Labels["severity"]
+	if slices.ContainsFunc(amber, func(s string) bool { return strings.EqualFold(s, severity) }) {
+		return api.Amber
+	}
+	return api.Red
+}
+
+func alertMessage(alert alertmanagerAlert) string {
+	message := alert.Annotations["summary"]
+	if message == "" {
+		message = alert.Annotations["description"]
+	}
+	if message == "" {
+		message = alert.Labels["alertname"]
+	}
+
+	if alert.Status == "resolved" {
+		return "Resolved: " + message
+	}
+	return message
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/baelish/alive/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestAlertBoxID(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		names  []string
+		expect string
+	}{
+		{
+			name:   "alertname and instance",
+			labels: map[string]string{"alertname": "HighLatency", "instance": "web1:9100", "job": "node"},
+			names:  []string{"alertname", "instance"},
+			expect: "HighLatency-web1-9100",
+		},
+		{
+			name:   "missing labels are skipped",
+			labels: map[string]string{"alertname": "Watchdog"},
+			names:  []string{"alertname", "instance"},
+			expect: "Watchdog",
+		},
+		{
+			name:   "no labels",
+			labels: map[string]string{"job": "node"},
+			names:  []string{"alertname", "instance"},
+			expect: "",
+		},
+		{
+			name:   "invalid characters",
+			labels: map[string]string{"service": "/api/v1 (eu)"},
+			names:  []string{"service"},
+			expect: "api-v1-eu",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := alertBoxID(tt.labels, tt.names); got != tt.expect {
+				t.Errorf("expected %q, got %q", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestAlertStatus(t *testing.T) {
+	amber := []string{"warning", "warn"}
+	tests := []struct {
+		name   string
+		alert  alertmanagerAlert
+		expect api.Status
+	}{
+		{"critical", alertmanagerAlert{Status: "firing", Labels: map[string]string{"severity": "critical"}}, api.Red},
+		{"warning", alertmanagerAlert{Status: "firing", Labels: map[string]string{"severity": "Warning"}}, api.Amber},
+		{"no severity", alertmanagerAlert{Status: "firing"}, api.Red},
+		{"resolved", alertmanagerAlert{Status: "resolved", Labels: map[string]string{"severity": "critical"}}, api.Green},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := alertStatus(tt.alert, amber); got != tt.expect {
+				t.Errorf("expected %s, got %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestApiAlertmanager(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	originalTemplates := templateStore.GetAll()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+		templateStore.Set(originalTemplates)
+	}()
+
+	templateStore.Put(api.Template{
+		Name: "alert",
+		Box: api.Box{
+			Name:   "{{.alertname}} on {{.instance}}",
+			Size:   api.Medium,
+			Labels: map[string]string{"team": "{{.team}}"},
+		},
+	})
+
+	router := chi.NewRouter()
+	router.Post("/api/v1/integrations/alertmanager", apiAlertmanager)
+
+	firing := `{"version": "4", "status": "firing", "alerts": [
+		{"status": "firing", "labels": {"alertname": "HighLatency", "instance": "web1", "severity": "warning", "team": "web"},
+		 "annotations": {"summary": "latency is high", "runbook_url": "https://wiki/latency"},
+		 "generatorURL": "http://prometheus/graph"}
+	]}`
+
+	tests := []struct {
+		name          string
+		existing      *api.Box
+		query         string
+		body          string
+		expectCode    int
+		expectID      string
+		expectStatus  api.Status
+		expectMessage string
+		expectName    string
+	}{
+		{
+			name:          "creates box",
+			body:          firing,
+			expectCode:    http.StatusOK,
+			expectID:      "HighLatency-web1",
+			expectStatus:  api.Amber,
+			expectMessage: "latency is high",
+			expectName:    "HighLatency web1",
+		},
+		{
+			name:          "updates existing box",
+			existing:      &api.Box{ID: "HighLatency-web1", Name: "mine", Status: api.Green},
+			body:          strings.ReplaceAll(firing, `"warning"`, `"critical"`),
+			expectCode:    http.StatusOK,
+			expectID:      "HighLatency-web1",
+			expectStatus:  api.Red,
+			expectMessage: "latency is high",
+			expectName:    "mine",
+		},
+		{
+			name:          "resolved",
+			existing:      &api.Box{ID: "HighLatency-web1", Name: "mine", Status: api.Red},
+			body:          strings.ReplaceAll(firing, `"status": "firing"`, `"status": "resolved"`),
+			expectCode:    http.StatusOK,
+			expectID:      "HighLatency-web1",
+			expectStatus:  api.Green,
+			expectMessage: "Resolved: latency is high",
+			expectName:    "mine",
+		},
+		{
+			name:          "configured labels",
+			query:         "?labels=team",
+			body:          firing,
+			expectCode:    http.StatusOK,
+			expectID:      "web",
+			expectStatus:  api.Amber,
+			expectMessage: "latency is high",
+			expectName:    "web",
+		},
+		{
+			name:          "configured amber severities",
+			query:         "?amber=info",
+			body:          firing,
+			expectCode:    http.StatusOK,
+			expectID:      "HighLatency-web1",
+			expectStatus:  api.Red,
+			expectMessage: "latency is high",
+		},
+		{
+			name:          "template",
+			query:         "?template=alert",
+			body:          firing,
+			expectCode:    http.StatusOK,
+			expectID:      "HighLatency-web1",
+			expectStatus:  api.Amber,
+			expectMessage: "latency is high",
+			expectName:    "HighLatency on web1",
+		},
+		{
+			name:       "template missing a label",
+			query:      "?template=alert",
+			body:       strings.ReplaceAll(firing, `"team": "web"`, `"job": "node"`),
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown template",
+			query:      "?template=missing",
+			body:       firing,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "no id labels",
+			query:      "?labels=cluster",
+			body:       firing,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid body",
+			body:       "{",
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetBoxStore()
+			if tt.existing != nil {
+				boxStore.Add(*tt.existing)
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/integrations/alertmanager"+tt.query, strings.NewReader(tt.body))
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.expectCode {
+				t.Fatalf("expected code %d, got %d: %s", tt.expectCode, rec.Code, rec.Body)
+			}
+			if tt.expectCode != http.StatusOK {
+				if boxStore.Len() != 0 && tt.existing == nil {
+					t.Errorf("expected no boxes to be created, got %d", boxStore.Len())
+				}
+				return
+			}
+
+			box, err := boxStore.GetByID(tt.expectID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if box.Status != tt.expectStatus {
+				t.Errorf("expected status %s, got %s", tt.expectStatus, box.Status)
+			}
+			if box.LastMessage != tt.expectMessage {
+				t.Errorf("expected message %q, got %q", tt.expectMessage, box.LastMessage)
+			}
+			if tt.expectName != "" && box.Name != tt.expectName {
+				t.Errorf("expected name %q, got %q", tt.expectName, box.Name)
+			}
+		})
+	}
+}
+
+func TestAlertBox(t *testing.T) {
+	alert := alertmanagerAlert{
+		Labels:       map[string]string{"alertname": "DiskFull", "instance": "db1"},
+		Annotations:  map[string]string{"description": "disk is **full**", "runbook_url": "javascript:alert(1)"},
+		GeneratorURL: "http://prometheus/graph",
+	}
+
+	box, err := alertBox("DiskFull-db1", alert, alertmanagerConfig{Labels: defaultAlertmanagerLabels}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if box.Description != "disk is **full**" {
+		t.Errorf("expected description from annotation, got %q", box.Description)
+	}
+	if box.Runbook != "" {
+		t.Errorf("expected invalid runbook to be dropped, got %q", box.Runbook)
+	}
+	if len(box.Links) != 1 || box.Links[0].URL != alert.GeneratorURL {
+		t.Errorf("expected a link to the source, got %v", box.Links)
+	}
+	if box.Labels["instance"] != "db1" {
+		t.Errorf("expected alert labels on the box, got %v", box.Labels)
+	}
+}
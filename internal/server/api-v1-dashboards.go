@@ -0,0 +1,149 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/baelish/alive/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func apiGetDashboards(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(dashboardStore.GetAll()); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func apiGetDashboard(w http.ResponseWriter, r *http.Request) {
+	d, ok := dashboardStore.Get(chi.URLParam(r, "slug"))
+	if !ok {
+		handleApiErrorResponse(w, http.StatusNotFound, nil, "dashboard not found", false, true)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func decodeDashboard(w http.ResponseWriter, r *http.Request) (api.Dashboard, bool) {
+	var d api.Dashboard
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, true)
+		return d, false
+	}
+
+	if slug := chi.URLParam(r, "slug"); slug != "" {
+		if d.Slug == "" {
+			d.Slug = slug
+		} else if d.Slug != slug {
+			handleApiErrorResponse(w, http.StatusBadRequest, nil, "dashboard slug does not match the path", false, true)
+			return d, false
+		}
+	}
+
+	if err := d.Validate(); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "invalid dashboard", true, true)
+		return d, false
+	}
+
+	return d, true
+}
+
+func apiCreateDashboard(w http.ResponseWriter, r *http.Request) {
+	d, ok := decodeDashboard(w, r)
+	if !ok {
+		return
+	}
+
+	if err := dashboardStore.PutIfAbsent(d); errors.Is(err, errNameExists) {
+		handleApiErrorResponse(w, http.StatusConflict, nil, "a dashboard already exists with that slug", false, true)
+		return
+	} else if err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to save dashboards", false, false)
+		return
+	}
+	// Open dashboards refresh to pick up the change.
+	events.messages <- resyncMessage
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/dashboards/%s", d.Slug))
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func apiReplaceDashboard(w http.ResponseWriter, r *http.Request) {
+	d, ok := decodeDashboard(w, r)
+	if !ok {
+		return
+	}
+
+	replaced, err := dashboardStore.Put(d)
+	if err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to save dashboards", false, false)
+		return
+	}
+	events.messages <- resyncMessage
+
+	w.Header().Set("Content-Type", "application/json")
+	if replaced {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func apiDeleteDashboard(w http.ResponseWriter, r *http.Request) {
+	found, err := dashboardStore.Delete(chi.URLParam(r, "slug"))
+	if err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to save dashboards", false, false)
+		return
+	}
+	if !found {
+		handleApiErrorResponse(w, http.StatusNotFound, nil, "dashboard not found", false, true)
+		return
+	}
+	events.messages <- resyncMessage
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiPutDashboardLayout changes the layout of a dashboard, like
+// apiPutLayout does for the dashboard at /.
+func apiPutDashboardLayout(w http.ResponseWriter, r *http.Request) {
+	var layout api.Layout
+	if err := json.NewDecoder(r.Body).Decode(&layout); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, true)
+		return
+	}
+
+	if err := layout.Validate(); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "invalid layout", true, true)
+		return
+	}
+
+	result, found, err := setDashboardLayout(chi.URLParam(r, "slug"), layout)
+	if !found {
+		handleApiErrorResponse(w, http.StatusNotFound, nil, "dashboard not found", false, true)
+		return
+	}
+	if err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to save layout", false, false)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.Error(err.Error())
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/baelish/alive/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Only this much of the JSON posted to a hook is read.
+const maxHookBodySize = 1024 * 1024
+
+func apiGetHooks(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(hookStore.GetAll()); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func apiGetHook(w http.ResponseWriter, r *http.Request) {
+	h, ok := hookStore.Get(chi.URLParam(r, "name"))
+	if !ok {
+		handleApiErrorResponse(w, http.StatusNotFound, nil, "hook not found", false, true)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func decodeHook(w http.ResponseWriter, r *http.Request) (api.Hook, bool) {
+	var h api.Hook
+	if err := json.NewDecoder(r.Body).Decode(&h); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, true)
+		return h, false
+	}
+
+	if h.Name == "" {
+		h.Name = chi.URLParam(r, "name")
+	}
+
+	if err := h.Validate(); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "invalid hook", true, true)
+		return h, false
+	}
+
+	if _, err := compileHook(h); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "invalid hook", true, true)
+		return h, false
+	}
+
+	return h, true
+}
+
+func apiCreateHook(w http.ResponseWriter, r *http.Request) {
+	h, ok := decodeHook(w, r)
+	if !ok {
+		return
+	}
+
+	if err := hookStore.PutIfAbsent(h); errors.Is(err, errNameExists) {
+		handleApiErrorResponse(w, http.StatusConflict, nil, "a hook already exists with that name", false, true)
+		return
+	} else if err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to save hooks", false, false)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/hooks/%s", h.Name))
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(h); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func apiReplaceHook(w http.ResponseWriter, r *http.Request) {
+	h, ok := decodeHook(w, r)
+	if !ok {
+		return
+	}
+
+	if h.Name != chi.URLParam(r, "name") {
+		handleApiErrorResponse(w, http.StatusBadRequest, nil, "hook name does not match the path", false, true)
+		return
+	}
+
+	replaced, err := hookStore.Put(h)
+	if err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to save hooks", false, false)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if replaced {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	if err := json.NewEncoder(w).Encode(h); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func apiDeleteHook(w http.ResponseWriter, r *http.Request) {
+	found, err := hookStore.Delete(chi.URLParam(r, "name"))
+	if err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to save hooks", false, false)
+		return
+	}
+	if !found {
+		handleApiErrorResponse(w, http.StatusNotFound, nil, "hook not found", false, true)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiReceiveHook turns JSON posted by another tool into an event, using the
+// hook named in the path.
+func apiReceiveHook(w http.ResponseWriter, r *http.Request) {
+	h, ok := hookStore.Get(chi.URLParam(r, "name"))
+	if !ok {
+		handleApiErrorResponse(w, http.StatusNotFound, nil, "hook not found", false, true)
+		return
+	}
+
+	hook, err := compileHook(h)
+	if err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "invalid hook", true, false)
+		return
+	}
+
+	var data any
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxHookBodySize))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, true)
+		return
+	}
+
+	event, err := hook.event(data)
+	if err != nil {
+		handleApiErrorResponse(w, http.StatusUnprocessableEntity, err, "could not make an event from the data received", true, true)
+		return
+	}
+
+	if err := update(event); err != nil {
+		if strings.Contains(err.Error(), "could not find box") {
+			handleApiErrorResponse(w, http.StatusNotFound, err, "box not found", true, true)
+		} else {
+			handleApiErrorResponse(w, http.StatusInternalServerError, err, "Internal server error", false, false)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(event); err != nil {
+		logger.Error(err.Error())
+	}
+}
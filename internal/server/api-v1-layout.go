@@ -0,0 +1,37 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/baelish/alive/api"
+)
+
+func apiGetLayout(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(currentLayout()); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+// apiPutLayout changes the layout, the groups are replaced if given and the
+// boxes listed are moved, other boxes are left where they are.
+func apiPutLayout(w http.ResponseWriter, r *http.Request) {
+	var layout api.Layout
+	if err := json.NewDecoder(r.Body).Decode(&layout); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, true)
+		return
+	}
+
+	if err := layout.Validate(); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "invalid layout", true, true)
+		return
+	}
+
+	if err := setLayout(layout); err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to save layout", false, false)
+		return
+	}
+
+	apiGetLayout(w, r)
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/baelish/alive/api"
+)
+
+// streamFilter limits the events sent on the API event stream, an empty
+// set matches everything.
+type streamFilter struct {
+	types    map[string]bool
+	ids      map[string]bool
+	statuses map[api.Status]bool
+}
+
+// queryValues returns all values of a query parameter, which may be repeated
+// or comma separated.
+func queryValues(query url.Values, key string) (values []string) {
+	for _, v := range query[key] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+
+	return values
+}
+
+func newStreamFilter(query url.Values) (*streamFilter, error) {
+	f := &streamFilter{
+		types:    make(map[string]bool),
+		ids:      make(map[string]bool),
+		statuses: make(map[api.Status]bool),
+	}
+
+	for _, t := range queryValues(query, "type") {
+		switch t {
+		case api.EventCreated, api.EventUpdated, api.EventDeleted, api.EventStatusChanged:
+			f.types[t] = true
+		default:
+			return nil, fmt.Errorf("invalid event type: %s", t)
+		}
+	}
+
+	for _, id := range queryValues(query, "id") {
+		f.ids[id] = true
+	}
+
+	for _, s := range queryValues(query, "status") {
+		var status api.Status
+		if err := json.Unmarshal([]byte(`"`+s+`"`), &status); err != nil {
+			return nil, fmt.Errorf("invalid status: %s", s)
+		}
+		f.statuses[status] = true
+	}
+
+	return f, nil
+}
+
+// translate turns a dashboard message into an event for the API stream,
+// returning false if it should not be sent.
+func (f *streamFilter) translate(data string) (string, bool) {
+	var event api.Event
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return "", false
+	}
+
+	switch event.Type {
+	case "keepalive", "resync":
+		// Always passed on so consumers know the stream is alive and when
+		// to fetch the boxes again.
+		return data, true
+	case "createBox":
+		if event.Box == nil {
+			return "", false
+		}
+		event.Type = api.EventCreated
+		event.ID = event.Box.ID
+		event.Status = event.Box.Status
+		event.After = ""
+	case "updateBox":
+		event.Type = api.EventUpdated
+		if event.PreviousStatus != nil && *event.PreviousStatus != event.Status {
+			event.Type = api.EventStatusChanged
+		}
+	case "deleteBox":
+		event.Type = api.EventDeleted
+	default:
+		return "", false
+	}
+
+	if len(f.types) > 0 && !f.types[event.Type] {
+		return "", false
+	}
+	if len(f.ids) > 0 && !f.ids[event.ID] {
+		return "", false
+	}
+	if len(f.statuses) > 0 && (event.Type == api.EventDeleted || !f.statuses[event.Status]) {
+		return "", false
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		logger.Error(err.Error())
+		return "", false
+	}
+
+	return string(b), true
+}
+
+// apiStream streams box events as server-sent events, each carrying an
+// api.Event. The events can be filtered with the type, id and status query
+// parameters.
+func apiStream(w http.ResponseWriter, r *http.Request) {
+	filter, err := newStreamFilter(r.URL.Query())
+	if err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "invalid filter", true, true)
+		return
+	}
+
+	events.serveEvents(w, r, filter.translate)
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/baelish/alive/api"
+)
+
+func TestStreamFilter_Translate(t *testing.T) {
+	initTestLogger()
+
+	tests := []struct {
+		name       string
+		query      string
+		data       string
+		expectOK   bool
+		expectType string
+	}{
+		{name: "keepalive", data: `{"type": "keepalive"}`, expectOK: true, expectType: api.EventKeepalive},
+		{name: "resync", query: "type=deleted", data: `{"type": "resync"}`, expectOK: true, expectType: api.EventResync},
+		{name: "create", data: `{"type": "createBox", "after": "x", "box": {"id": "box1", "name": "Box 1", "status": "green"}}`, expectOK: true, expectType: api.EventCreated},
+		{name: "update", data: `{"type": "updateBox", "id": "box1", "status": "green", "previousStatus": "green"}`, expectOK: true, expectType: api.EventUpdated},
+		{name: "status change", data: `{"type": "updateBox", "id": "box1", "status": "red", "previousStatus": "green"}`, expectOK: true, expectType: api.EventStatusChanged},
+		{name: "delete", data: `{"type": "deleteBox", "id": "box1"}`, expectOK: true, expectType: api.EventDeleted},
+		{name: "dashboard only", data: `{"type": "reloadPage"}`, expectOK: false},
+		{name: "filtered by type", query: "type=created,deleted", data: `{"type": "updateBox", "id": "box1", "status": "red"}`, expectOK: false},
+		{name: "filtered by id", query: "id=box2", data: `{"type": "updateBox", "id": "box1", "status": "red"}`, expectOK: false},
+		{name: "matches id", query: "id=box2&id=box1", data: `{"type": "updateBox", "id": "box1", "status": "red"}`, expectOK: true, expectType: api.EventUpdated},
+		{name: "filtered by status", query: "status=red", data: `{"type": "updateBox", "id": "box1", "status": "green"}`, expectOK: false},
+		{name: "matches status", query: "status=red", data: `{"type": "createBox", "box": {"id": "box1", "status": "red"}}`, expectOK: true, expectType: api.EventCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			filter, err := newStreamFilter(query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			data, ok := filter.translate(tt.data)
+			if ok != tt.expectOK {
+				t.Fatalf("expected ok=%v, got %v", tt.expectOK, ok)
+			}
+			if !ok {
+				return
+			}
+
+			var event api.Event
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatalf("failed to decode event: %v", err)
+			}
+			if event.Type != tt.expectType {
+				t.Errorf("expected type %q, got %q", tt.expectType, event.Type)
+			}
+			if event.After != "" {
+				t.Errorf("expected dashboard only fields to be removed, got after=%q", event.After)
+			}
+		})
+	}
+}
+
+func TestNewStreamFilter_Invalid(t *testing.T) {
+	for _, query := range []string{"type=createBox", "status=purple"} {
+		values, _ := url.ParseQuery(query)
+		if _, err := newStreamFilter(values); err == nil {
+			t.Errorf("expected error for %q", query)
+		}
+	}
+}
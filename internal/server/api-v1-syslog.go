@@ -0,0 +1,38 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/baelish/alive/api"
+)
+
+func apiGetSyslogRules(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(syslogRuleStore.Rules()); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+// apiPutSyslogRules replaces the syslog rules, which are tried in order.
+func apiPutSyslogRules(w http.ResponseWriter, r *http.Request) {
+	rules := []api.SyslogRule{}
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, true)
+		return
+	}
+
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			handleApiErrorResponse(w, http.StatusBadRequest, err, "invalid syslog rules", true, true)
+			return
+		}
+	}
+
+	if err := syslogRuleStore.Replace(rules); err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to save syslog rules", false, false)
+		return
+	}
+
+	apiGetSyslogRules(w, r)
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/baelish/alive/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func apiGetTemplates(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(templateStore.GetAll()); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func apiGetTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := templateStore.Get(chi.URLParam(r, "name"))
+	if !ok {
+		handleApiErrorResponse(w, http.StatusNotFound, nil, "template not found", false, true)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func decodeTemplate(w http.ResponseWriter, r *http.Request) (api.Template, bool) {
+	var t api.Template
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, true)
+		return t, false
+	}
+
+	if t.Name == "" {
+		t.Name = chi.URLParam(r, "name")
+	}
+
+	if err := t.Validate(); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "invalid template", true, true)
+		return t, false
+	}
+
+	if _, err := executeTemplate(t, nil); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "invalid template", true, true)
+		return t, false
+	}
+
+	return t, true
+}
+
+func apiCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := decodeTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	if err := templateStore.PutIfAbsent(t); errors.Is(err, errNameExists) {
+		handleApiErrorResponse(w, http.StatusConflict, nil, "a template already exists with that name", false, true)
+		return
+	} else if err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to save templates", false, false)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/templates/%s", t.Name))
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+// apiReplaceTemplate replaces a template, with ?propagate=true the boxes
+// created from it are updated to match.
+func apiReplaceTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := decodeTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	if t.Name != chi.URLParam(r, "name") {
+		handleApiErrorResponse(w, http.StatusBadRequest, nil, "template name does not match the path", false, true)
+		return
+	}
+
+	replaced, err := templateStore.Put(t)
+	if err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to save templates", false, false)
+		return
+	}
+
+	if r.URL.Query().Get("propagate") == "true" {
+		if _, err := propagateTemplate(t); err != nil {
+			handleApiErrorResponse(w, http.StatusUnprocessableEntity, err, "template saved but some boxes could not be updated", true, true)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if replaced {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func apiDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	found, err := templateStore.Delete(chi.URLParam(r, "name"))
+	if err != nil {
+		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to save templates", false, false)
+		return
+	}
+	if !found {
+		handleApiErrorResponse(w, http.StatusNotFound, nil, "template not found", false, true)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiInstantiateTemplate creates a box from a template, or several if sent a
+// list of instances.
+func apiInstantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := templateStore.Get(chi.URLParam(r, "name"))
+	if !ok {
+		handleApiErrorResponse(w, http.StatusNotFound, nil, "template not found", false, true)
+		return
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, true)
+		return
+	}
+
+	var instances []api.TemplateInstance
+	list := bytes.HasPrefix(bytes.TrimSpace(raw), []byte("["))
+	if list {
+		if err := json.Unmarshal(raw, &instances); err != nil {
+			handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, true)
+			return
+		}
+	} else {
+		var instance api.TemplateInstance
+		if err := json.Unmarshal(raw, &instance); err != nil {
+			handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, true)
+			return
+		}
+		instances = append(instances, instance)
+	}
+
+	// Render them all first so nothing is created if any are invalid.
+	boxes := make([]api.Box, 0, len(instances))
+	for _, instance := range instances {
+		box, err := renderTemplate(t, instance)
+		if err != nil {
+			handleApiErrorResponse(w, http.StatusBadRequest, err, "could not create box from template", true, true)
+			return
+		}
+		boxes = append(boxes, box)
+	}
+
+	for i := range boxes {
+		id, err := addBox(boxes[i])
+		if err != nil {
+			handleApiErrorResponse(w, http.StatusConflict, err, fmt.Sprintf("created %d of %d boxes", i, len(boxes)), true, true)
+			return
+		}
+		boxes[i].ID = id
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !list {
+		w.Header().Set("Location", fmt.Sprintf("/api/v1/boxes/%s", boxes[0].ID))
+	}
+	w.WriteHeader(http.StatusCreated)
+
+	var resp any = boxes
+	if !list {
+		resp = boxes[0]
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Error(err.Error())
+	}
+}
@@ -67,18 +67,41 @@ func apiGetBox(w http.ResponseWriter, r *http.Request) {
 }
 
 func apiCreateEvent(w http.ResponseWriter, r *http.Request) {
-	var event api.Event
-	err := json.NewDecoder(r.Body).Decode(&event)
+	var body json.RawMessage
+	err := json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
 		handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, false)
 
 		return
 	}
 
+	var event api.Event
+	// Events with metrics can leave the status to the box's thresholds.
+	var given struct {
+		Status *json.RawMessage `json:"status"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, false)
+
+		return
+	}
+	if err := json.Unmarshal(body, &given); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "could not decode data received", true, false)
+
+		return
+	}
+	hasStatus := given.Status != nil || len(event.Metrics) == 0
+
+	if err := event.Validate(); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "invalid event", true, true)
+
+		return
+	}
+
 	event.ID = chi.URLParam(r, "id")
 	event.Type = "updateBox"
 	logger.Debug("update event details", logStructDetails(event)...)
-	err = update(event)
+	err = applyEvent(event, hasStatus)
 	if err != nil {
 		if strings.Contains(err.Error(), "could not find box") {
 			handleApiErrorResponse(w, http.StatusNotFound, err, "box not found", true, false)
@@ -105,6 +128,12 @@ func apiCreateBox(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateBox(newBox); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "invalid box", true, true)
+
+		return
+	}
+
 	id, err := addBox(newBox)
 	if err != nil {
 		handleApiErrorResponse(w, http.StatusInternalServerError, err, "failed to create the box", false, false)
@@ -137,6 +166,11 @@ func apiReplaceBox(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateBox(newBox); err != nil {
+		handleApiErrorResponse(w, http.StatusBadRequest, err, "invalid box", true, true)
+		return
+	}
+
 	// Delete old box and add new one (both thread-safe)
 	found, oldBox := deleteBox(newBox.ID, true)
 	if _, err := addBox(newBox); err != nil {
@@ -205,12 +239,50 @@ func runAPI(_ context.Context) {
 	}
 	router := chi.NewRouter()
 	router.Get("/health", apiStatus)
+	router.Handle("/metrics", metricsHandler())
 	router.Get("/api/v1/boxes", apiGetBoxes)                 // Get all boxes
 	router.Post("/api/v1/boxes", apiCreateBox)               // Create a new box
 	router.Put("/api/v1/boxes/{id}", apiReplaceBox)          // Replace an existing box
 	router.Delete("/api/v1/boxes/{id}", apiDeleteBox)        // Delete a box
 	router.Get("/api/v1/boxes/{id}", apiGetBox)              // Get a specific box
 	router.Post("/api/v1/boxes/{id}/events", apiCreateEvent) // Create a box event
+	router.Get("/api/v1/stream", apiStream)                  // Stream box events
+
+	router.Get("/api/v1/layout", apiGetLayout) // Get the dashboard layout
+	router.Put("/api/v1/layout", apiPutLayout) // Change the dashboard layout
+
+	router.Get("/api/v1/dashboards", apiGetDashboards)                    // Get all dashboards
+	router.Post("/api/v1/dashboards", apiCreateDashboard)                 // Create a dashboard
+	router.Get("/api/v1/dashboards/{slug}", apiGetDashboard)              // Get a specific dashboard
+	router.Put("/api/v1/dashboards/{slug}", apiReplaceDashboard)          // Replace a dashboard
+	router.Delete("/api/v1/dashboards/{slug}", apiDeleteDashboard)        // Delete a dashboard
+	router.Put("/api/v1/dashboards/{slug}/layout", apiPutDashboardLayout) // Change a dashboard's layout
+
+	router.Get("/api/v1/templates", apiGetTemplates)                            // Get all templates
+	router.Post("/api/v1/templates", apiCreateTemplate)                         // Create a template
+	router.Get("/api/v1/templates/{name}", apiGetTemplate)                      // Get a specific template
+	router.Put("/api/v1/templates/{name}", apiReplaceTemplate)                  // Replace a template
+	router.Delete("/api/v1/templates/{name}", apiDeleteTemplate)                // Delete a template
+	router.Post("/api/v1/templates/{name}/instantiate", apiInstantiateTemplate) // Create boxes from a template
+
+	router.Get("/api/v1/hooks", apiGetHooks)             // Get all hooks
+	router.Post("/api/v1/hooks", apiCreateHook)          // Create a hook
+	router.Get("/api/v1/hooks/{name}", apiGetHook)       // Get a specific hook
+	router.Put("/api/v1/hooks/{name}", apiReplaceHook)   // Replace a hook
+	router.Delete("/api/v1/hooks/{name}", apiDeleteHook) // Delete a hook
+	router.Post("/api/v1/hooks/{name}", apiReceiveHook)  // Update a box from JSON posted to a hook
+
+	router.Get("/api/v1/syslog/rules", apiGetSyslogRules) // Get the syslog rules
+	router.Put("/api/v1/syslog/rules", apiPutSyslogRules) // Replace the syslog rules
+
+	router.Post("/api/v1/integrations/alertmanager", apiAlertmanager) // Alertmanager webhook receiver
+
+	// Pings for cron jobs, GET or POST with an optional message as the body.
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		router.Method(method, "/ping/{id}", pingHandler(pingSuccess))
+		router.Method(method, "/ping/{id}/fail", pingHandler(pingFail))
+		router.Method(method, "/ping/{id}/start", pingHandler(pingStart))
+	}
 
 	// Old paths, Deprecated.
 	router.Get("/api/v1/box", DeprecatedRoute("this path is depricated. use GET /api/v1/boxes instead")(apiGetBoxes))
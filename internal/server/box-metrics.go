@@ -0,0 +1,137 @@
+package server
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baelish/alive/api"
+)
+
+// The number of values kept for each metric on a box.
+const maxMetricPoints = 60
+
+// recordMetrics adds the values sent on an event to the box's metrics.
+func recordMetrics(box *api.Box, values []api.MetricValue, t time.Time) {
+	if len(values) == 0 {
+		return
+	}
+
+	// Copies of the box share the old metrics, so build new ones.
+	metrics := slices.Clone(box.Metrics)
+	for _, v := range values {
+		i := slices.IndexFunc(metrics, func(m api.Metric) bool { return m.Name == v.Name })
+		if i < 0 {
+			metrics = append(metrics, api.Metric{Name: v.Name})
+			i = len(metrics) - 1
+		}
+
+		m := &metrics[i]
+		if v.Unit != "" {
+			m.Unit = v.Unit
+		}
+		start := max(0, len(m.Points)+1-maxMetricPoints)
+		points := make([]api.MetricPoint, 0, len(m.Points)+1-start)
+		points = append(points, m.Points[start:]...)
+		m.Points = append(points, api.MetricPoint{Value: v.Value, Time: t})
+	}
+	box.Metrics = metrics
+}
+
+// derivedStatus works out a status from the thresholds on the box whose
+// metrics were sent on the event, returning false if there aren't any.
+func derivedStatus(box *api.Box, values []api.MetricValue) (api.Status, string, bool) {
+	status := api.Green
+	var messages []string
+	applied := false
+
+	for _, t := range box.Thresholds {
+		i := slices.IndexFunc(values, func(v api.MetricValue) bool { return v.Name == t.Metric })
+		if i < 0 {
+			continue
+		}
+		applied = true
+
+		s, message := t.Status(values[i].Value)
+		if message != "" {
+			messages = append(messages, message)
+		}
+		if s.Worse(status) {
+			status = s
+		}
+	}
+
+	return status, strings.Join(messages, ", "), applied
+}
+
+// formatMetric returns the latest value of a metric with its unit.
+func formatMetric(m api.Metric) string {
+	value, ok := m.Latest()
+	if !ok {
+		return ""
+	}
+	s := strconv.FormatFloat(value, 'g', -1, 64)
+	if m.Unit != "" {
+		s += " " + m.Unit
+	}
+	return s
+}
+
+// sparkline draws a metric's recent values as an SVG line, scaled to fill
+// the element it is shown in. scripts.js redraws it as values arrive.
+func sparkline(m api.Metric) template.HTML {
+	values := make([]string, 0, len(m.Points))
+	for _, p := range m.Points {
+		values = append(values, strconv.FormatFloat(p.Value, 'g', -1, 64))
+	}
+
+	return template.HTML(fmt.Sprintf(
+		`<svg class='sparkline' data-metric='%s' data-values='%s' viewBox='0 0 100 20' preserveAspectRatio='none'><title>%s: %s</title><polyline points='%s'/></svg>`,
+		html.EscapeString(m.Name),
+		strings.Join(values, ","),
+		html.EscapeString(m.Name),
+		html.EscapeString(formatMetric(m)),
+		sparklinePoints(m.Points),
+	))
+}
+
+func sparklinePoints(points []api.MetricPoint) string {
+	if len(points) == 0 {
+		return ""
+	}
+	if len(points) == 1 {
+		points = []api.MetricPoint{points[0], points[0]}
+	}
+
+	low, high := points[0].Value, points[0].Value
+	for _, p := range points {
+		low = min(low, p.Value)
+		high = max(high, p.Value)
+	}
+
+	coords := make([]string, 0, len(points))
+	for i, p := range points {
+		x := float64(i) * 100 / float64(len(points)-1)
+		y := 10.0
+		if high > low {
+			// Leave a little room so the line isn't cut off.
+			y = 19 - (p.Value-low)/(high-low)*18
+		}
+		coords = append(coords, fmt.Sprintf("%.2f,%.2f", x, y))
+	}
+
+	return strings.Join(coords, " ")
+}
+
+// tileSparkline returns the sparkline shown on a tile, for the box's first
+// metric on tiles large enough to show one.
+func tileSparkline(box api.Box) template.HTML {
+	if box.Size < api.Medium || len(box.Metrics) == 0 {
+		return ""
+	}
+	return sparkline(box.Metrics[0])
+}
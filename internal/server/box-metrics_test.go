@@ -0,0 +1,198 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baelish/alive/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRecordMetrics(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	box := api.Box{}
+
+	recordMetrics(&box, []api.MetricValue{{Name: "queue", Value: 1, Unit: "jobs"}, {Name: "lag", Value: 0.5}}, now)
+	shared := box
+	for i := range maxMetricPoints + 5 {
+		recordMetrics(&box, []api.MetricValue{{Name: "queue", Value: float64(i + 2)}}, now.Add(time.Duration(i)*time.Second))
+	}
+
+	if len(box.Metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(box.Metrics))
+	}
+	queue := box.Metrics[0]
+	if queue.Name != "queue" || queue.Unit != "jobs" || len(queue.Points) != maxMetricPoints {
+		t.Errorf("expected %d queue points in jobs, got %+v", maxMetricPoints, queue)
+	}
+	if latest, _ := queue.Latest(); latest != maxMetricPoints+6 {
+		t.Errorf("expected the latest value to be kept, got %g", latest)
+	}
+	if len(shared.Metrics[0].Points) != 1 {
+		t.Errorf("expected copies of the box to be left alone, got %d points", len(shared.Metrics[0].Points))
+	}
+}
+
+func TestDerivedStatus(t *testing.T) {
+	amber, red := 100.0, 1000.0
+	box := api.Box{Thresholds: []api.Threshold{
+		{Metric: "queue", Amber: &amber, Red: &red},
+		{Metric: "lag", Amber: &amber},
+	}}
+
+	tests := []struct {
+		name    string
+		values  []api.MetricValue
+		status  api.Status
+		message string
+		applied bool
+	}{
+		{name: "no metrics", applied: false},
+		{name: "other metric", values: []api.MetricValue{{Name: "other", Value: 5000}}, applied: false},
+		{name: "green", values: []api.MetricValue{{Name: "queue", Value: 5}}, status: api.Green, applied: true},
+		{name: "worst wins", values: []api.MetricValue{{Name: "lag", Value: 200}, {Name: "queue", Value: 2000}}, status: api.Red, message: "queue 2000 >= 1000, lag 200 >= 100", applied: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, message, applied := derivedStatus(&box, tt.values)
+			if applied != tt.applied {
+				t.Fatalf("expected applied %t, got %t", tt.applied, applied)
+			}
+			if applied && (status != tt.status || message != tt.message) {
+				t.Errorf("expected %s %q, got %s %q", tt.status, tt.message, status, message)
+			}
+		})
+	}
+}
+
+func TestEvent_UpdateMetrics(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+	}()
+
+	resetBoxStore()
+
+	red := 1000.0
+	boxStore.Add(api.Box{ID: "queue", Name: "Queue", Status: api.Grey, Thresholds: []api.Threshold{{Metric: "depth", Red: &red}}})
+
+	if err := update(api.Event{ID: "queue", Status: api.Green, Metrics: []api.MetricValue{{Name: "depth", Value: 1234}}}); err != nil {
+		t.Fatalf("failed to send update, %s", err.Error())
+	}
+
+	b, err := boxStore.GetByID("queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Status != api.Red || b.LastMessage != "depth 1234 >= 1000" {
+		t.Errorf("expected the threshold to set the box red, got %s %q", b.Status, b.LastMessage)
+	}
+	if len(b.Metrics) != 1 || len(b.Metrics[0].Points) != 1 {
+		t.Errorf("expected the metric to be recorded, got %+v", b.Metrics)
+	}
+
+	// A worse status sent on the event isn't replaced by the threshold.
+	if err := update(api.Event{ID: "queue", Status: api.Amber, Message: "draining", Metrics: []api.MetricValue{{Name: "depth", Value: 5}}}); err != nil {
+		t.Fatalf("failed to send update, %s", err.Error())
+	}
+	if b, _ = boxStore.GetByID("queue"); b.Status != api.Amber || b.LastMessage != "draining" {
+		t.Errorf("expected the event's status to be kept, got %s %q", b.Status, b.LastMessage)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		points string
+	}{
+		{name: "no values", points: ""},
+		{name: "one value", values: []float64{5}, points: "0.00,10.00 100.00,10.00"},
+		{name: "rising", values: []float64{0, 5, 10}, points: "0.00,19.00 50.00,10.00 100.00,1.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var points []api.MetricPoint
+			for _, v := range tt.values {
+				points = append(points, api.MetricPoint{Value: v})
+			}
+			if got := sparklinePoints(points); got != tt.points {
+				t.Errorf("expected %q, got %q", tt.points, got)
+			}
+		})
+	}
+
+	metric := api.Metric{Name: "<depth>", Unit: "jobs", Points: []api.MetricPoint{{Value: 3}}}
+	if got := tileSparkline(api.Box{Size: api.Small, Metrics: []api.Metric{metric}}); got != "" {
+		t.Errorf("expected no sparkline on a small tile, got %s", got)
+	}
+	got := string(tileSparkline(api.Box{Size: api.Medium, Metrics: []api.Metric{metric}}))
+	if !strings.Contains(got, "data-metric='&lt;depth&gt;'") || !strings.Contains(got, "<title>&lt;depth&gt;: 3 jobs</title>") {
+		t.Errorf("expected an escaped sparkline, got %s", got)
+	}
+}
+
+func TestApiCreateEvent_Thresholds(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+	}()
+
+	resetBoxStore()
+
+	threshold, err := api.ParseThreshold("latency_ms > 500 => amber, > 2000 => red")
+	if err != nil {
+		t.Fatal(err)
+	}
+	boxStore.Add(api.Box{ID: "api", Name: "API", Status: api.Green, Thresholds: []api.Threshold{threshold}})
+
+	router := chi.NewRouter()
+	router.Post("/api/v1/boxes/{id}/events", apiCreateEvent)
+
+	tests := []struct {
+		name   string
+		body   string
+		status api.Status
+	}{
+		{name: "values only", body: `{"metrics": [{"name": "latency_ms", "value": 800}]}`, status: api.Amber},
+		{name: "worse threshold status wins", body: `{"status": "green", "metrics": [{"name": "latency_ms", "value": 2500}]}`, status: api.Red},
+		{name: "given status kept when worse", body: `{"status": "red", "metrics": [{"name": "latency_ms", "value": 800}]}`, status: api.Red},
+		{name: "other metrics keep the status", body: `{"metrics": [{"name": "errors", "value": 3}]}`, status: api.Red},
+		{name: "status without metrics", body: `{"status": "green"}`, status: api.Green},
+		{name: "no status or metrics", body: `{}`, status: api.Grey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/boxes/api/events", strings.NewReader(tt.body)))
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("expected %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body)
+			}
+
+			box, err := boxStore.GetByID("api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if box.Status != tt.status {
+				t.Errorf("expected %s, got %s", tt.status, box.Status)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/boxes/api/events", strings.NewReader(`{"metrics": [{"value": 1}]}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unnamed metric: expected %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"sort"
 	"sync"
 	"time"
@@ -142,15 +143,10 @@ func (bs *BoxStore) Len() int {
 	return len(bs.boxes)
 }
 
-// sortUnsafe sorts boxes (must be called with lock held)
+// sortUnsafe sorts boxes into the order they are shown on the dashboard (must
+// be called with lock held)
 func (bs *BoxStore) sortUnsafe() {
-	Size := func(p1, p2 *api.Box) bool {
-		if p1.Size == p2.Size {
-			return p1.Name < p2.Name
-		}
-		return int(p1.Size) > int(p2.Size)
-	}
-	by(Size).Sort(bs.boxes)
+	by(layoutLess(layoutStore.groupIndexes())).Sort(bs.boxes)
 }
 
 type by func(p1, p2 *api.Box) bool
@@ -196,6 +192,7 @@ func addBox(box api.Box) (id string, err error) {
 	}
 
 	box.LastUpdate = t
+	recordStatus(&box, t)
 
 	if box.MaxTBU != nil && box.MaxTBU.Duration() == 0 {
 		box.MaxTBU = nil
@@ -221,14 +218,10 @@ func addBox(box api.Box) (id string, err error) {
 	if err != nil {
 		logger.Error(err.Error())
 	}
-	if i == 0 {
-		event.After = "status-bar"
-	} else {
-		// Get the box before this one
-		allBoxes := boxStore.GetAll()
-		if i > 0 && i <= len(allBoxes) {
-			event.After = allBoxes[i-1].ID
-		}
+	// Get the element this box goes after
+	allBoxes := boxStore.GetAll()
+	if i >= 0 && i < len(allBoxes) {
+		event.After = precedingElementID(allBoxes, i)
 	}
 
 	stringData, err := json.Marshal(event)
@@ -282,7 +275,23 @@ func maintainBoxes() (boxesToDelete []string, boxesToUpdate []api.Event) {
 			}
 		}
 
-		if box.MaxTBU != nil {
+		if box.Schedule != nil {
+			// The schedule replaces MaxTBU, the box is late once the next
+			// run after its last update plus the grace period has passed.
+			next := box.Schedule.Next(lastUpdate)
+			if !next.IsZero() && time.Now().After(next.Add(box.Grace.Duration())) && box.Status != api.NoUpdate {
+				if logger != nil {
+					logger.Warn("marking box for no-update event", zap.String("id", box.ID))
+				}
+				var event api.Event
+				event.ID = box.ID
+				event.Status = api.NoUpdate
+				event.Message = fmt.Sprintf("No update for the run due at %s.", next.Format("2006-01-02 15:04 MST"))
+				event.Type = api.NoUpdate.String()
+				boxesToUpdate = append(boxesToUpdate, event)
+				return true // continue
+			}
+		} else if box.MaxTBU != nil {
 			if time.Since(lastUpdate) > box.MaxTBU.Duration() && box.Status != api.NoUpdate {
 				if logger != nil {
 					logger.Warn("marking box for no-update event", zap.String("id", box.ID))
@@ -296,6 +305,20 @@ func maintainBoxes() (boxesToDelete []string, boxesToUpdate []api.Event) {
 				return true // continue
 			}
 		}
+
+		if runOverdue(box) && (box.Status == api.Green || box.Status == api.Grey) {
+			if logger != nil {
+				logger.Warn("marking box with overdue run", zap.String("id", box.ID))
+			}
+			// Not a check-in so uses the noUpdate type to leave LastUpdate
+			// alone.
+			var event api.Event
+			event.ID = box.ID
+			event.Status = api.Amber
+			event.Message = fmt.Sprintf("Run started %s ago, longer than the expected %s.", time.Since(*box.RunStarted).Round(time.Second), box.ExpectedDuration)
+			event.Type = api.NoUpdate.String()
+			boxesToUpdate = append(boxesToUpdate, event)
+		}
 		return true // continue
 	})
 	return boxesToDelete, boxesToUpdate
@@ -350,11 +373,34 @@ func maintenanceRoutine(ctx context.Context) {
 }
 
 func update(event api.Event) error {
+	return applyEvent(event, true)
+}
+
+// applyEvent updates a box from an event. hasStatus is false for events which
+// only carry metrics, the box then keeps its status unless a threshold sets
+// it.
+func applyEvent(event api.Event, hasStatus bool) error {
 	t := time.Now()
 	const maxMessages = 30
 
 	// Update box in store (thread-safe)
 	err := boxStore.Update(event.ID, func(box *api.Box) {
+		previous := box.Status
+		event.PreviousStatus = &previous
+		event.Quiet = box.Quiet
+
+		// A status sent on the event, or set by a check, is only made worse by
+		// the box's thresholds.
+		if status, message, ok := derivedStatus(box, event.Metrics); ok && (!hasStatus || status.Worse(event.Status)) {
+			event.Status = status
+			if event.Message == "" {
+				event.Message = message
+			}
+		} else if !hasStatus {
+			event.Status = box.Status
+		}
+		recordMetrics(box, event.Metrics, t)
+
 		box.LastMessage = event.Message
 
 		// Prepend new message
@@ -378,7 +424,20 @@ func update(event api.Event) error {
 			box.LastUpdate = t
 		}
 
+		if len(event.Info) > 0 {
+			info := make(map[string]string)
+			if box.Info != nil {
+				maps.Copy(info, *box.Info)
+			}
+			maps.Copy(info, event.Info)
+			box.Info = &info
+		}
+
+		if box.Status != event.Status {
+			box.Acknowledged = false
+		}
 		box.Status = event.Status
+		recordStatus(box, t)
 		if event.MaxTBU != nil {
 			if *event.MaxTBU == api.Duration(0) {
 				box.MaxTBU = nil
@@ -401,6 +460,8 @@ func update(event api.Event) error {
 		return err
 	}
 
+	eventsIngested.Inc()
+
 	event.Type = "updateBox"
 	dataString, err := json.Marshal(event)
 	if err != nil {
@@ -411,3 +472,25 @@ func update(event api.Event) error {
 
 	return nil
 }
+
+// ackBox marks a box as acknowledged until its status next changes.
+func ackBox(id string) error {
+	err := boxStore.Update(id, func(box *api.Box) {
+		box.Acknowledged = true
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("acknowledged box", zap.String("id", id))
+
+	event := api.Event{Type: "ackBox", ID: id}
+	dataString, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	events.messages <- string(dataString)
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/json"
 	"os"
 	"reflect"
 	"testing"
@@ -495,6 +496,32 @@ func TestMaintainBoxes_NoDeadlock(t *testing.T) {
 	}
 }
 
+func TestMaintainBoxes_Schedule(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+	}()
+
+	resetBoxStore()
+	now := time.Now()
+	hourly := &api.Schedule{Cron: "@hourly"}
+	boxStore.Add(api.Box{ID: "missed", Status: api.Green, LastUpdate: now.Add(-2 * time.Hour), Schedule: hourly})
+	boxStore.Add(api.Box{ID: "in-grace", Status: api.Green, LastUpdate: now.Add(-2 * time.Hour), Schedule: hourly, Grace: ptr(api.Duration(3 * time.Hour))})
+	boxStore.Add(api.Box{ID: "on-time", Status: api.Green, LastUpdate: now, Schedule: hourly})
+	// The schedule replaces MaxTBU.
+	boxStore.Add(api.Box{ID: "schedule-wins", Status: api.Green, LastUpdate: now.Add(-time.Minute), Schedule: &api.Schedule{Cron: "@yearly"}, MaxTBU: ptr(api.Duration(time.Second))})
+
+	_, toUpdate := maintainBoxes()
+	if len(toUpdate) != 1 {
+		t.Fatalf("expected 1 update, got %d: %v", len(toUpdate), toUpdate)
+	}
+	if toUpdate[0].ID != "missed" || toUpdate[0].Status != api.NoUpdate {
+		t.Errorf("expected missed to be noUpdate, got %s %s", toUpdate[0].ID, toUpdate[0].Status)
+	}
+}
+
 func TestEvent_Update(t *testing.T) {
 	originalBoxes := boxStore.GetAll()
 	defer func() {
@@ -584,6 +611,78 @@ func TestEvent_Update(t *testing.T) {
 		}
 	}
 }
+
+func TestEvent_UpdateInfo(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+	}()
+
+	resetBoxStore()
+
+	id := "test-box"
+	boxStore.Add(api.Box{
+		ID:     id,
+		Name:   "Info",
+		Status: api.Grey,
+		Info:   &map[string]string{"owner": "ops", "load1": "0.1"},
+	})
+
+	if err := update(api.Event{ID: id, Status: api.Green, Info: map[string]string{"load1": "0.5"}}); err != nil {
+		t.Fatalf("failed to send update, %s", err.Error())
+	}
+
+	b, err := boxStore.GetByID(id)
+	if err != nil {
+		t.Fatalf("unable to get expected box %s (%s)", id, err.Error())
+	}
+	expectEqual(t, b.Info, &map[string]string{"owner": "ops", "load1": "0.5"})
+}
+
+func TestEvent_UpdateQuiet(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	originalEvents := events
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+		events = originalEvents
+	}()
+
+	resetBoxStore()
+	events = &Broker{messages: make(chan string, 10)}
+
+	boxStore.Add(api.Box{ID: "loud", Name: "Loud", Status: api.Green})
+	boxStore.Add(api.Box{ID: "quiet", Name: "Quiet", Status: api.Green, Quiet: true})
+
+	tests := []struct {
+		id    string
+		quiet bool
+	}{
+		{id: "loud", quiet: false},
+		{id: "quiet", quiet: true},
+	}
+
+	for _, tt := range tests {
+		if err := update(api.Event{ID: tt.id, Status: api.Red}); err != nil {
+			t.Fatalf("failed to send update, %s", err.Error())
+		}
+
+		var event api.Event
+		if err := json.Unmarshal([]byte(<-events.messages), &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Quiet != tt.quiet {
+			t.Errorf("%s: expected quiet %t, got %t", tt.id, tt.quiet, event.Quiet)
+		}
+		if event.PreviousStatus == nil || *event.PreviousStatus != api.Green {
+			t.Errorf("%s: expected previous status green, got %v", tt.id, event.PreviousStatus)
+		}
+	}
+}
+
 func expectEqual(t *testing.T, actual any, expected any) {
 	if !reflect.DeepEqual(actual, expected) {
 		t.Errorf("expected %v, got %v", expected, actual)
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/baelish/alive/api"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// prometheusMessage is what a prometheus check's message template is given.
+type prometheusMessage struct {
+	Value   float64
+	Metrics map[string]float64
+}
+
+func runPrometheusCheck(ctx context.Context, check api.Check) api.Event {
+	families, err := scrapePrometheus(ctx, check.URL)
+	if err != nil {
+		return checkResult(api.Red, "scrape of %s failed: %s", check.URL, err)
+	}
+
+	family, ok := families[check.Metric]
+	if !ok {
+		return checkResult(api.Red, "metric %s not found", check.Metric)
+	}
+
+	var values []float64
+	for _, m := range family.GetMetric() {
+		if hasLabels(m, check.Labels) {
+			value, err := sampleValue(family.GetType(), m)
+			if err != nil {
+				return checkResult(api.Red, "metric %s %s", check.Metric, err)
+			}
+			values = append(values, value)
+		}
+	}
+	switch {
+	case len(values) == 0:
+		return checkResult(api.Red, "no series of %s has labels %s", check.Metric, formatLabels(check.Labels))
+	case len(values) > 1:
+		return checkResult(api.Red, "%d series of %s have labels %s, add labels to choose one", len(values), check.Metric, formatLabels(check.Labels))
+	}
+	value := values[0]
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return checkResult(api.Red, "metric %s value is not a number: %g", check.Metric, value)
+	}
+
+	event := checkResult(api.Green, "%s %s", check.Metric, strconv.FormatFloat(value, 'g', -1, 64))
+	event.Metrics = []api.MetricValue{{Name: check.Metric, Value: value}}
+	if check.Threshold != nil {
+		var message string
+		if event.Status, message = check.Threshold.Status(value); message != "" {
+			event.Message = message
+		}
+	}
+
+	if check.Message != "" {
+		message, err := prometheusCheckMessage(check.Message, value, families)
+		if err != nil {
+			return checkResult(api.Red, "invalid message: %s", err)
+		}
+		event.Message = message
+	}
+
+	return event
+}
+
+// scrapePrometheus fetches the metrics from a Prometheus endpoint.
+func scrapePrometheus(ctx context.Context, url string) (map[string]*dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+
+	resp, err := checkHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	families := make(map[string]*dto.MetricFamily)
+	decoder := expfmt.NewDecoder(io.LimitReader(resp.Body, maxCheckBodySize), expfmt.ResponseFormat(resp.Header))
+	for {
+		family := &dto.MetricFamily{}
+		if err := decoder.Decode(family); err != nil {
+			if errors.Is(err, io.EOF) {
+				return families, nil
+			}
+			return nil, err
+		}
+		families[family.GetName()] = family
+	}
+}
+
+func hasLabels(m *dto.Metric, labels map[string]string) bool {
+	for name, value := range labels {
+		i := slices.IndexFunc(m.GetLabel(), func(l *dto.LabelPair) bool { return l.GetName() == name })
+		if i < 0 || m.GetLabel()[i].GetValue() != value {
+			return false
+		}
+	}
+	return true
+}
+
+func sampleValue(t dto.MetricType, m *dto.Metric) (float64, error) {
+	switch t {
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue(), nil
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue(), nil
+	case dto.MetricType_UNTYPED:
+		return m.GetUntyped().GetValue(), nil
+	}
+	return 0, fmt.Errorf("is a %s, only counters, gauges and untyped metrics can be checked", strings.ToLower(t.String()))
+}
+
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, labels[name]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// prometheusCheckMessage fills in a check's message template, giving it the
+// value checked and the first value of every metric scraped.
+func prometheusCheckMessage(text string, value float64, families map[string]*dto.MetricFamily) (string, error) {
+	t, err := template.New("message").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	data := prometheusMessage{Value: value, Metrics: make(map[string]float64)}
+	for name, family := range families {
+		if metrics := family.GetMetric(); len(metrics) > 0 {
+			if v, err := sampleValue(family.GetType(), metrics[0]); err == nil {
+				data.Metrics[name] = v
+			}
+		}
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
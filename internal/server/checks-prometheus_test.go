@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/baelish/alive/api"
+)
+
+const prometheusExposition = `# HELP minecraft_status_healthy Whether the server is healthy.
+# TYPE minecraft_status_healthy gauge
+minecraft_status_healthy 1
+# TYPE minecraft_status_players_online_count gauge
+minecraft_status_players_online_count 3
+# TYPE minecraft_status_players_max_count gauge
+minecraft_status_players_max_count 20
+# TYPE http_requests_total counter
+http_requests_total{code="200",handler="/"} 1027
+http_requests_total{code="500",handler="/"} 3
+# TYPE ratio gauge
+ratio NaN
+# TYPE request_seconds summary
+request_seconds_sum 12
+request_seconds_count 4
+`
+
+func TestRunPrometheusCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, prometheusExposition)
+	}))
+	defer server.Close()
+
+	threshold := func(s string) *api.Threshold {
+		th, err := api.ParseThreshold(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &th
+	}
+	metrics := server.URL + "/metrics"
+
+	tests := []struct {
+		name          string
+		check         api.Check
+		expectStatus  api.Status
+		expectMessage string
+	}{
+		{name: "value", check: api.Check{URL: metrics, Metric: "minecraft_status_healthy"}, expectStatus: api.Green, expectMessage: "minecraft_status_healthy 1"},
+		{name: "threshold", check: api.Check{URL: metrics, Metric: "minecraft_status_players_online_count", Threshold: threshold("minecraft_status_players_online_count > 2 => amber")}, expectStatus: api.Amber, expectMessage: "minecraft_status_players_online_count 3 > 2"},
+		{name: "labels", check: api.Check{URL: metrics, Metric: "http_requests_total", Labels: map[string]string{"code": "500"}, Threshold: threshold("http_requests_total > 0 => red")}, expectStatus: api.Red, expectMessage: "http_requests_total 3 > 0"},
+		{name: "message", check: api.Check{URL: metrics, Metric: "minecraft_status_healthy", Threshold: threshold("minecraft_status_healthy < 1 => red"), Message: `{{ index .Metrics "minecraft_status_players_online_count" }}/{{ index .Metrics "minecraft_status_players_max_count" }} Online`}, expectStatus: api.Green, expectMessage: "3/20 Online"},
+		{name: "ambiguous", check: api.Check{URL: metrics, Metric: "http_requests_total"}, expectStatus: api.Red, expectMessage: `2 series of http_requests_total have labels {}, add labels to choose one`},
+		{name: "no matching series", check: api.Check{URL: metrics, Metric: "http_requests_total", Labels: map[string]string{"code": "404"}}, expectStatus: api.Red, expectMessage: `no series of http_requests_total has labels {code="404"}`},
+		{name: "missing metric", check: api.Check{URL: metrics, Metric: "nope"}, expectStatus: api.Red, expectMessage: "metric nope not found"},
+		{name: "not a number", check: api.Check{URL: metrics, Metric: "ratio"}, expectStatus: api.Red, expectMessage: "metric ratio value is not a number: NaN"},
+		{name: "unsupported type", check: api.Check{URL: metrics, Metric: "request_seconds"}, expectStatus: api.Red, expectMessage: "metric request_seconds is a summary, only counters, gauges and untyped metrics can be checked"},
+		{name: "not found", check: api.Check{URL: server.URL + "/missing", Metric: "up"}, expectStatus: api.Red},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check.Type = "prometheus"
+			event := runCheck(context.Background(), tt.check)
+			if event.Status != tt.expectStatus {
+				t.Errorf("expected %s, got %s (%s)", tt.expectStatus, event.Status, event.Message)
+			}
+			if tt.expectMessage != "" && event.Message != tt.expectMessage {
+				t.Errorf("expected message %q, got %q", tt.expectMessage, event.Message)
+			}
+		})
+	}
+
+	event := runCheck(context.Background(), api.Check{Type: "prometheus", URL: metrics, Metric: "minecraft_status_players_online_count"})
+	expectEqual(t, event.Metrics, []api.MetricValue{{Name: "minecraft_status_players_online_count", Value: 3}})
+}
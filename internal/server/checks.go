@@ -0,0 +1,358 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baelish/alive/api"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultCheckInterval = 1 * time.Minute
+	defaultCheckTimeout  = 10 * time.Second
+
+	// Only this much of a response body is matched against BodyRegex.
+	maxCheckBodySize = 1 << 20
+
+	// Only this much of what a TCP server sends is matched against
+	// BannerRegex.
+	maxCheckBannerSize = 4096
+)
+
+var checkHTTPClient = &http.Client{}
+
+// checkScheduler runs the checks configured on boxes when they are due.
+type checkScheduler struct {
+	mu      sync.Mutex
+	running map[string]bool
+	nextRun map[string]time.Time
+	wg      sync.WaitGroup
+}
+
+func newCheckScheduler() *checkScheduler {
+	return &checkScheduler{
+		running: make(map[string]bool),
+		nextRun: make(map[string]time.Time),
+	}
+}
+
+// runDue starts any checks which are due and not already running.
+func (s *checkScheduler) runDue(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	boxStore.ForEach(func(box api.Box) bool {
+		if box.Check == nil {
+			return true // continue
+		}
+		seen[box.ID] = true
+
+		if s.running[box.ID] || now.Before(s.nextRun[box.ID]) {
+			return true // continue
+		}
+
+		interval := box.Check.Interval.Duration()
+		if interval <= 0 {
+			interval = defaultCheckInterval
+		}
+		s.nextRun[box.ID] = now.Add(interval)
+		s.running[box.ID] = true
+
+		s.wg.Add(1)
+		go s.run(ctx, box.ID, *box.Check)
+
+		return true // continue
+	})
+
+	// Forget boxes which have gone or no longer have a check.
+	for id := range s.nextRun {
+		if !seen[id] {
+			delete(s.nextRun, id)
+		}
+	}
+}
+
+func (s *checkScheduler) run(ctx context.Context, id string, check api.Check) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, id)
+		s.mu.Unlock()
+	}()
+
+	event := runCheck(ctx, check)
+	if ctx.Err() != nil {
+		return
+	}
+
+	event.ID = id
+	if err := event.Validate(); err != nil {
+		event = checkResult(api.Red, "invalid check result: %s", err)
+		event.ID = id
+	}
+	logger.Debug("check complete", zap.String("id", id), zap.String("status", event.Status.String()), zap.String("message", event.Message))
+	if err := update(event); err != nil {
+		logger.Warn("failed to update box with check result", zap.String("id", id), zap.Error(err))
+	}
+}
+
+// validateBox validates a box sent to the API, also rejecting exec checks
+// unless they are enabled on this server.
+func validateBox(box api.Box) error {
+	if err := box.Validate(); err != nil {
+		return err
+	}
+
+	if box.Check != nil && box.Check.Type == "exec" && !options.ExecChecks {
+		return errors.New("exec checks are not enabled on this server")
+	}
+
+	return nil
+}
+
+func checkResult(status api.Status, format string, a ...any) api.Event {
+	return api.Event{Status: status, Message: fmt.Sprintf(format, a...)}
+}
+
+// runCheck runs a check and returns the event to update the box with.
+func runCheck(ctx context.Context, check api.Check) api.Event {
+	timeout := check.Timeout.Duration()
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch check.Type {
+	case "", "http":
+		return runHTTPCheck(ctx, check)
+	case "tcp":
+		return runTCPCheck(ctx, check)
+	case "exec":
+		if !options.ExecChecks {
+			return checkResult(api.Red, "exec checks are not enabled on this server")
+		}
+		return runExecCheck(ctx, check)
+	case "prometheus":
+		return runPrometheusCheck(ctx, check)
+	default:
+		return checkResult(api.Red, "unknown check type %q", check.Type)
+	}
+}
+
+func runHTTPCheck(ctx context.Context, check api.Check) api.Event {
+	method := check.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, check.URL, nil)
+	if err != nil {
+		return checkResult(api.Red, "invalid request: %s", err)
+	}
+
+	start := time.Now()
+	resp, err := checkHTTPClient.Do(req)
+	if err != nil {
+		return checkResult(api.Red, "%s %s failed: %s", method, check.URL, err)
+	}
+	defer resp.Body.Close()
+	latency := time.Since(start)
+
+	if check.ExpectStatus != 0 && resp.StatusCode != check.ExpectStatus {
+		return checkResult(api.Red, "unexpected status %s, expected %d", resp.Status, check.ExpectStatus)
+	}
+	if check.ExpectStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return checkResult(api.Red, "unexpected status %s", resp.Status)
+	}
+
+	if check.BodyRegex != "" {
+		re, err := regexp.Compile(check.BodyRegex)
+		if err != nil {
+			return checkResult(api.Red, "invalid bodyRegex: %s", err)
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxCheckBodySize))
+		if err != nil {
+			return checkResult(api.Red, "failed to read body: %s", err)
+		}
+
+		if !re.Match(body) {
+			return checkResult(api.Red, "body does not match %q", check.BodyRegex)
+		}
+	}
+
+	return checkResult(latencyStatus(check, latency), "%s in %s", resp.Status, latency.Round(time.Millisecond))
+}
+
+func runTCPCheck(ctx context.Context, check api.Check) api.Event {
+	var dialer net.Dialer
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", check.Address)
+	if err != nil {
+		return checkResult(api.Red, "connect to %s failed: %s", check.Address, err)
+	}
+	defer conn.Close()
+	latency := time.Since(start)
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if check.Send != "" {
+		if _, err := conn.Write([]byte(check.Send)); err != nil {
+			return checkResult(api.Red, "send to %s failed: %s", check.Address, err)
+		}
+	}
+
+	if check.BannerRegex != "" {
+		re, err := regexp.Compile(check.BannerRegex)
+		if err != nil {
+			return checkResult(api.Red, "invalid bannerRegex: %s", err)
+		}
+
+		// Keep reading until the banner matches, the server stops sending
+		// or we run out of time.
+		var banner []byte
+		buf := make([]byte, 512)
+		for !re.Match(banner) {
+			if len(banner) >= maxCheckBannerSize {
+				return checkResult(api.Red, "banner does not match %q", check.BannerRegex)
+			}
+
+			n, err := conn.Read(buf)
+			banner = append(banner, buf[:n]...)
+			if err != nil && !re.Match(banner) {
+				return checkResult(api.Red, "banner does not match %q: %s", check.BannerRegex, err)
+			}
+		}
+	}
+
+	return checkResult(latencyStatus(check, latency), "connected to %s in %s", check.Address, latency.Round(time.Millisecond))
+}
+
+func runExecCheck(ctx context.Context, check api.Check) api.Event {
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, check.Command[0], check.Command[1:]...)
+	cmd.Stdout = &stdout
+	// Don't wait for children which keep stdout open after the command is
+	// killed.
+	cmd.WaitDelay = 100 * time.Millisecond
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return checkResult(api.Red, "%s timed out", check.Command[0])
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return checkResult(api.Red, "failed to run %s: %s", check.Command[0], err)
+	}
+
+	var event api.Event
+	switch cmd.ProcessState.ExitCode() {
+	case 0:
+		event.Status = api.Green
+	case 1:
+		event.Status = api.Amber
+	case 2:
+		event.Status = api.Red
+	default:
+		event.Status = api.Grey
+	}
+
+	// Nagios style output, "message | perfdata" on the first line.
+	line, _, _ := strings.Cut(stdout.String(), "\n")
+	message, perfdata, _ := strings.Cut(line, "|")
+	event.Message = strings.TrimSpace(message)
+
+	if check.Perfdata {
+		event.Info = parsePerfdata(perfdata)
+	}
+
+	return event
+}
+
+// parsePerfdata parses Nagios plugin performance data
+// ('label'=value[UOM];[warn];[crit];[min];[max] ...) returning the value, with
+// its unit, of each label.
+func parsePerfdata(perfdata string) map[string]string {
+	values := make(map[string]string)
+
+	for perfdata = strings.TrimSpace(perfdata); perfdata != ""; perfdata = strings.TrimSpace(perfdata) {
+		var label string
+		if strings.HasPrefix(perfdata, "'") {
+			var ok bool
+			if label, perfdata, ok = strings.Cut(perfdata[1:], "'="); !ok {
+				break
+			}
+		} else {
+			var ok bool
+			if label, perfdata, ok = strings.Cut(perfdata, "="); !ok {
+				break
+			}
+		}
+
+		var value string
+		value, perfdata, _ = strings.Cut(perfdata, " ")
+		value, _, _ = strings.Cut(value, ";")
+		if label != "" && value != "" {
+			values[label] = value
+		}
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	return values
+}
+
+// latencyStatus returns the status for a successful check which took latency.
+func latencyStatus(check api.Check, latency time.Duration) api.Status {
+	if check.RedLatency != nil && latency > check.RedLatency.Duration() {
+		return api.Red
+	}
+
+	if check.AmberLatency != nil && latency > check.AmberLatency.Duration() {
+		return api.Amber
+	}
+
+	return api.Green
+}
+
+// Run the checks configured on boxes.
+func runChecks(ctx context.Context) {
+	if options.Debug {
+		logger.Info("Starting check scheduler")
+	}
+
+	s := newCheckScheduler()
+	for {
+		s.runDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			if options.Debug {
+				logger.Info("Stopping check scheduler")
+			}
+			return
+
+		case <-time.After(1 * time.Second):
+		}
+	}
+}
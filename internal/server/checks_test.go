@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/baelish/alive/api"
+)
+
+func TestRunHTTPCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			fmt.Fprint(w, `{"status": "healthy"}`)
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+			fmt.Fprint(w, "ok")
+		case "/teapot":
+			w.WriteHeader(http.StatusTeapot)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		check        api.Check
+		expectStatus api.Status
+	}{
+		{name: "ok", check: api.Check{URL: server.URL + "/ok"}, expectStatus: api.Green},
+		{name: "body matches", check: api.Check{URL: server.URL + "/ok", BodyRegex: `"status": "healthy"`}, expectStatus: api.Green},
+		{name: "body does not match", check: api.Check{URL: server.URL + "/ok", BodyRegex: "unhealthy"}, expectStatus: api.Red},
+		{name: "not found", check: api.Check{URL: server.URL + "/missing"}, expectStatus: api.Red},
+		{name: "expected status", check: api.Check{URL: server.URL + "/teapot", ExpectStatus: http.StatusTeapot}, expectStatus: api.Green},
+		{name: "unexpected status", check: api.Check{URL: server.URL + "/ok", ExpectStatus: http.StatusNoContent}, expectStatus: api.Red},
+		{name: "slow", check: api.Check{URL: server.URL + "/slow", AmberLatency: ptr(api.Duration(10 * time.Millisecond))}, expectStatus: api.Amber},
+		{name: "timeout", check: api.Check{URL: server.URL + "/slow", Timeout: ptr(api.Duration(10 * time.Millisecond))}, expectStatus: api.Red},
+		{name: "connection refused", check: api.Check{URL: "http://127.0.0.1:1"}, expectStatus: api.Red},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := runCheck(context.Background(), tt.check)
+			if event.Status != tt.expectStatus {
+				t.Errorf("expected %s, got %s (%s)", tt.expectStatus, event.Status, event.Message)
+			}
+			if event.Message == "" {
+				t.Error("expected a message")
+			}
+		})
+	}
+}
+
+func TestCheckScheduler(t *testing.T) {
+	initTestLogger()
+
+	originalBoxes := boxStore.GetAll()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+	}()
+	resetBoxStore()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	boxStore.Add(api.Box{ID: "checked", Name: "Checked", Status: api.Grey, Check: &api.Check{URL: server.URL, Interval: ptr(api.Duration(time.Minute))}})
+	boxStore.Add(api.Box{ID: "unchecked", Name: "Unchecked", Status: api.Grey})
+
+	s := newCheckScheduler()
+	now := time.Now()
+	s.runDue(context.Background(), now)
+	s.wg.Wait()
+
+	box, _ := boxStore.GetByID("checked")
+	if box.Status != api.Red {
+		t.Errorf("expected checked box to be red, got %s", box.Status)
+	}
+	if len(box.Messages) != 1 {
+		t.Errorf("expected the check result in the box messages, got %d messages", len(box.Messages))
+	}
+
+	box, _ = boxStore.GetByID("unchecked")
+	if box.Status != api.Grey {
+		t.Errorf("expected unchecked box to be untouched, got %s", box.Status)
+	}
+
+	// Not due again until the interval has passed
+	s.runDue(context.Background(), now.Add(30*time.Second))
+	s.wg.Wait()
+	if requests != 1 {
+		t.Errorf("expected 1 request before the interval passed, got %d", requests)
+	}
+
+	s.runDue(context.Background(), now.Add(61*time.Second))
+	s.wg.Wait()
+	if requests != 2 {
+		t.Errorf("expected 2 requests after the interval passed, got %d", requests)
+	}
+}
+
+func TestRunTCPCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// A server which sends a banner then echoes back whatever it is sent.
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fmt.Fprint(conn, "220 alive test ready\r\n")
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	address := listener.Addr().String()
+	tests := []struct {
+		name         string
+		check        api.Check
+		expectStatus api.Status
+	}{
+		{name: "port open", check: api.Check{Type: "tcp", Address: address}, expectStatus: api.Green},
+		{name: "banner matches", check: api.Check{Type: "tcp", Address: address, BannerRegex: `^220 `}, expectStatus: api.Green},
+		{name: "banner does not match", check: api.Check{Type: "tcp", Address: address, BannerRegex: `^500 `, Timeout: ptr(api.Duration(50 * time.Millisecond))}, expectStatus: api.Red},
+		{name: "reply to payload matches", check: api.Check{Type: "tcp", Address: address, Send: "PING\r\n", BannerRegex: `PING`}, expectStatus: api.Green},
+		{name: "slow connection is red", check: api.Check{Type: "tcp", Address: address, AmberLatency: ptr(api.Duration(0)), RedLatency: ptr(api.Duration(0))}, expectStatus: api.Red},
+		{name: "slow connection is amber", check: api.Check{Type: "tcp", Address: address, AmberLatency: ptr(api.Duration(0)), RedLatency: ptr(api.Duration(time.Minute))}, expectStatus: api.Amber},
+		{name: "connection refused", check: api.Check{Type: "tcp", Address: "127.0.0.1:1"}, expectStatus: api.Red},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := runCheck(context.Background(), tt.check)
+			if event.Status != tt.expectStatus {
+				t.Errorf("expected %s, got %s (%s)", tt.expectStatus, event.Status, event.Message)
+			}
+		})
+	}
+}
+
+func TestRunExecCheck(t *testing.T) {
+	oldExecChecks := options.ExecChecks
+	defer func() { options.ExecChecks = oldExecChecks }()
+
+	sh := func(script string) []string { return []string{"sh", "-c", script} }
+
+	tests := []struct {
+		name          string
+		execChecks    bool
+		check         api.Check
+		expectStatus  api.Status
+		expectMessage string
+		expectInfo    map[string]string
+	}{
+		{name: "ok", execChecks: true, check: api.Check{Type: "exec", Command: sh(`echo "OK - all fine"`)}, expectStatus: api.Green, expectMessage: "OK - all fine"},
+		{name: "warning", execChecks: true, check: api.Check{Type: "exec", Command: sh(`echo "WARNING - disk 85%"; exit 1`)}, expectStatus: api.Amber, expectMessage: "WARNING - disk 85%"},
+		{name: "critical", execChecks: true, check: api.Check{Type: "exec", Command: sh(`echo "CRITICAL - disk 99%"; exit 2`)}, expectStatus: api.Red, expectMessage: "CRITICAL - disk 99%"},
+		{name: "unknown", execChecks: true, check: api.Check{Type: "exec", Command: sh(`echo "UNKNOWN - no disk"; exit 3`)}, expectStatus: api.Grey, expectMessage: "UNKNOWN - no disk"},
+		{name: "only first line", execChecks: true, check: api.Check{Type: "exec", Command: sh(`printf "OK - first\nsecond\n"`)}, expectStatus: api.Green, expectMessage: "OK - first"},
+		{
+			name:          "perfdata",
+			execChecks:    true,
+			check:         api.Check{Type: "exec", Command: sh(`echo "OK - load fine | load1=0.5;1;2;0 'free space'=20GB;5;1"`), Perfdata: true},
+			expectStatus:  api.Green,
+			expectMessage: "OK - load fine",
+			expectInfo:    map[string]string{"load1": "0.5", "free space": "20GB"},
+		},
+		{name: "perfdata ignored", execChecks: true, check: api.Check{Type: "exec", Command: sh(`echo "OK - load fine | load1=0.5"`)}, expectStatus: api.Green, expectMessage: "OK - load fine"},
+		{name: "timeout", execChecks: true, check: api.Check{Type: "exec", Command: sh(`sleep 5`), Timeout: ptr(api.Duration(50 * time.Millisecond))}, expectStatus: api.Red},
+		{name: "missing command", execChecks: true, check: api.Check{Type: "exec", Command: []string{"/nonexistent/check"}}, expectStatus: api.Red},
+		{name: "disabled", check: api.Check{Type: "exec", Command: sh(`echo "OK"`)}, expectStatus: api.Red},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options.ExecChecks = tt.execChecks
+
+			event := runCheck(context.Background(), tt.check)
+			if event.Status != tt.expectStatus {
+				t.Errorf("expected %s, got %s (%s)", tt.expectStatus, event.Status, event.Message)
+			}
+			if tt.expectMessage != "" && event.Message != tt.expectMessage {
+				t.Errorf("expected message %q, got %q", tt.expectMessage, event.Message)
+			}
+			if !reflect.DeepEqual(event.Info, tt.expectInfo) {
+				t.Errorf("expected info %v, got %v", tt.expectInfo, event.Info)
+			}
+		})
+	}
+}
+
+func TestValidateBox(t *testing.T) {
+	oldExecChecks := options.ExecChecks
+	defer func() { options.ExecChecks = oldExecChecks }()
+
+	box := api.Box{Name: "disk", Check: &api.Check{Type: "exec", Command: []string{"check_disk"}}}
+
+	options.ExecChecks = false
+	if err := validateBox(box); err == nil {
+		t.Error("expected exec check to be rejected when disabled")
+	}
+
+	options.ExecChecks = true
+	if err := validateBox(box); err != nil {
+		t.Errorf("expected exec check to be accepted when enabled, got %v", err)
+	}
+}
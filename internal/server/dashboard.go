@@ -6,7 +6,12 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/baelish/alive/api"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
@@ -17,12 +22,14 @@ const dashboard = `
 <!DOCTYPE html>
 {{ template "head" . }}
 <html>
-	<body onresize='rightSizeBigBox("dashboard");' onload='rightSizeBigBox("dashboard"); keepalive();'>
+	<body {{ if .Kiosk }}class='kiosk' {{ end }}onresize='rightSizeBigBox("dashboard");' onload='rightSizeBigBox("dashboard"); keepalive(); startKiosk();'>
 		<input type="hidden" id="refreshed" value="no">
-		<div id='big-box' class='big-box'>
+		<div id='big-box' class='big-box' data-box-grid>
 			{{ template "statusBar" . }}
+			{{ template "layoutControls" . }}
 			{{ template "boxGrid" . }}
 		</div>
+		<div id='flash' class='flash'></div>
 	</body>
 </html>
 {{ end }}`
@@ -36,7 +43,38 @@ const infoPage = `
 	<input type="hidden" id="refreshed" value="no">
 		<div id='big-box' class='big-box'>
 		    {{ template "statusBar" . }}
-		    {{ template "boxInfo" . }}
+		    {{ template "boxInfo" .Box }}
+		    {{ template "boxHistory" . }}
+		</div>
+	</body>
+</html>
+{{ end }}`
+
+const dashboardList = `
+{{ define "dashboardList" }}
+<!DOCTYPE html>
+{{ template "head" . }}
+<html>
+	<body onresize='rightSizeBigBox();' onload='rightSizeBigBox(); keepalive();'>
+		<input type="hidden" id="refreshed" value="no">
+		<div id='big-box' class='big-box'>
+			{{ template "statusBar" . }}
+			<div class='fullwidth dashboards'>
+				<h2>Dashboards</h2>
+				<ul>
+				{{ range .Dashboards }}
+					<li><a href='/d/{{ .Slug }}{{ if $.Theme }}?theme={{ $.Theme }}{{ end }}'>{{ if .Title }}{{ .Title }}{{ else }}{{ .Slug }}{{ end }}</a></li>
+				{{ end }}
+				</ul>
+				{{ if .Themes }}
+				<h2>Themes</h2>
+				<ul>
+				{{ range .Themes }}
+					<li><a href='?theme={{ . }}'>{{ . }}</a></li>
+				{{ end }}
+				</ul>
+				{{ end }}
+			</div>
 		</div>
 	</body>
 </html>
@@ -45,78 +83,234 @@ const infoPage = `
 const generic = `
 {{ define "head" }}
 <head>
+  {{ if .Title }}<title>{{ .Title }}</title>{{ end }}
   <link rel='stylesheet' type='text/css' href='/static/standard.css'/>
+  {{ if .Theme }}<link rel='stylesheet' type='text/css' href='/static/theme-{{ .Theme }}.css'/>{{ end }}
   <script src='/static/scripts.js'></script>
 </head>
 {{ end }}
 
 {{ define "statusBar" }}
-<div id='status-bar' class='status fullwidth box'>
+<div id='status-bar' class='status fullwidth box{{ with .Summary }} {{ .Worst }}{{ end }}'{{ with .Summary }} data-health='{{ .Worst }}'{{ end }}>
   <p class='title'>Status</p>
+  {{ with .Summary }}
+  <p class='summary'>{{ range .Counts }}<span class='count {{ .Status }}{{ if not .Count }} zero{{ end }}' data-status='{{ .Status }}' onclick='filterStatus("{{ .Status }}")'>{{ .Count }} {{ .Status }}</span>{{ end }}</p>
+  {{ end }}
   <p class='tooltip' id='tooltip' display="none"></p>
 <p class='message' display="none"></p>
 <p class='lastUpdated'></p>
+<details id='alert-controls' class='alert-controls'>
+  <summary title='Alerts'>&#128276;</summary>
+  <label><input type='checkbox' id='alert-notify' onchange='saveAlertSettings()'> Desktop notifications</label>
+  <label><input type='checkbox' id='alert-sound' onchange='saveAlertSettings()'> Sound</label>
+  <input type='url' id='alert-sound-url' placeholder='Sound URL (optional)' onchange='saveAlertSettings()'>
+</details>
 </div>
 {{ end }}`
 
 const boxGrid = `
+{{ define "layoutControls" }}
+{{ if .Editable }}
+<div id='layout-controls' class='layout-controls'>
+  <button class='edit-layout' onclick='toggleEditMode()'>Edit layout</button>
+  <button class='add-section' onclick='addSection()'>Add section</button>
+</div>
+{{ end }}
+{{ end }}
+
 {{ define "boxGrid" }}
-  {{ range . }}
-    {{ template "box" . }}
+  {{ range .Sections }}
+    {{ if .Group.ID }}
+    <div class='section' data-group='{{ .Group.ID }}'>
+      <h2 class='heading' id='group-{{ .Group.ID }}'>{{ .Group.Title }}</h2>
+    {{ end }}
+    {{ range .Boxes }}
+      {{ template "box" . }}
+    {{ end }}
+    {{ if .Group.ID }}
+    </div>
+    {{ end }}
   {{ end }}
 {{ end }}
 
 {{ define "box" }}
-<div onclick='boxClick(this.id)' onmouseover='boxHover("{{ .Name }}")' onmouseout='boxOut()' id='{{ .ID }}' class='{{ .Status }} {{ .Size }} box'>
+<div onclick='boxClick(this.id)' onmouseover='boxHover("{{ .Name }}")' onmouseout='boxOut()' id='{{ .ID }}' class='{{ .Status }} {{ .Size }}{{ if .Acknowledged }} acknowledged{{ end }} tile box'>
     <p class='title'>{{ if .DisplayName }}{{ .DisplayName }}{{ else }}{{ .Name }}{{ end }}</p>
     <p class='message'>{{ .LastMessage }}</p>
+    {{ TileSparkline . }}
     <p class='lastUpdated'>{{ .LastUpdate.Format "2006-01-02T15:04:05.000Z07:00"}}</p>
     <p class='maxTBU'>{{ .MaxTBU }}</p>
     <p class='expireAfter'>{{ .ExpireAfter }}</p>
+    {{ if .Runbook }}<a class='runbook' href='{{ .Runbook }}' target='_blank' rel='noopener noreferrer' onclick='event.stopPropagation()'>Runbook</a>{{ end }}
 </div>
 {{ end }}`
 
 const boxInfo = `
 {{ define "boxInfo" }}
-<div id="{{ .ID }}" class="{{ .Status }} fullwidth info box">
+<div id="{{ .ID }}" class="{{ .Status }} fullwidth info box{{ if .Acknowledged }} acknowledged{{ end }}">
   <h2>{{ .Name }}</h2>
+  {{ if .Runbook }}<a class="runbook" href="{{ .Runbook }}" target="_blank" rel="noopener noreferrer">Runbook</a>{{ end }}
+  <button class="ack" onclick='acknowledge("{{ .ID }}")'>Acknowledge</button>
   {{ if .Links }}{{ range .Links }}<a href="{{ .URL }}" target="_blank" rel="noopener noreferrer">{{ .Name }}</a><br />{{ end }}{{ end }}
 
   <table>
   {{ if .DisplayName }}<tr><th>Display name:</th><td>{{ .DisplayName }}</td></tr>{{ end }}
-  {{ if .Description }}<tr><th>Description:</th><td>{{ .Description }}</td></tr>{{ end }}
+  {{ if .Description }}<tr><th>Description:</th><td class="description">{{ .Description | Markdown }}</td></tr>{{ end }}
   <tr><th>ID:</th><td>{{ .ID }}</td></tr>
   <tr><th>Status:</th><td>{{ .Status }}</td></tr>
   <tr><th>Size:</th><td>{{ .Size }}</td></tr>
+  {{ if .Quiet }}<tr class="quiet"><th>Quiet:</th><td>yes, no notifications are raised</td></tr>{{ end }}
+  {{ if .Metrics }}<tr><th>Metrics:</th><td><table class="metrics">{{ range .Metrics }}<tr><th>{{ .Name }}:</th><td class="metric-value" data-metric="{{ .Name }}">{{ FormatMetric . }}</td><td class="metric-sparkline">{{ Sparkline . }}</td></tr>{{ end }}</table></td></tr>{{ end }}
+  {{ if .Thresholds }}<tr class="thresholds"><th>Thresholds:</th><td><ul>{{ range .Thresholds }}<li>{{ if .Conditions }}{{ . }}{{ else }}{{ .Metric }}{{ if .Below }} at or below{{ else }} at or above{{ end }}{{ with .Amber }} {{ . }} is amber{{ end }}{{ if and .Amber .Red }},{{ end }}{{ with .Red }} {{ . }} is red{{ end }}{{ end }}</li>{{ end }}</ul></td></tr>{{ end }}
   {{ if .Info }}<tr><th>Info:</th><td><table>{{ range $key, $value := .Info }}<tr><th>{{ $key }}:</th><td>{{ $value }}</td></tr>{{ end }}</table></td></tr>{{ end }}
   <tr><th>Last message:</th><td class="message">{{ .LastMessage }}</td></tr>
   <tr><th>Last updated:</th><td class="lastUpdated">{{ .LastUpdate.Format "2006-01-02T15:04:05.000Z07:00" }}</td></tr>
   <tr class="maxTBU" {{ if not .MaxTBU }}style="display: none;"{{ end }}><th>Max TBU:</th><td>{{ .MaxTBU }}</td></tr>
   <tr class="expireAfter" {{ if not .ExpireAfter }}style="display: none;"{{ end }}><th>Expires after:</th><td>{{ .ExpireAfter }}</td></tr>
-  <tr><th>Previous Messages:</th><td><ul class="previousMessages">{{ range $m := .Messages }}<li>{{ $m.TimeStamp.Format "2006-01-02T15:04:05.000Z07:00" }}: {{ $m.Status | ToUpper }} ({{ $m.Message }})</li>{{ end }}</ul></td></tr>
+  {{ if .Schedule }}<tr class="schedule"><th>Schedule:</th><td>{{ .Schedule.Cron }}{{ if .Schedule.Timezone }} ({{ .Schedule.Timezone }}){{ end }}{{ if .Grace }}, grace {{ .Grace }}{{ end }}</td></tr>{{ end }}
+  {{ if .ExpectedDuration }}<tr class="expectedDuration"><th>Expected duration:</th><td>{{ .ExpectedDuration }}</td></tr>{{ end }}
+  {{ if .RunStarted }}<tr class="runStarted"><th>Run started:</th><td>{{ .RunStarted.Format "2006-01-02T15:04:05.000Z07:00" }}</td></tr>{{ end }}
+  {{ if .LastDuration }}<tr class="lastDuration"><th>Last run took:</th><td>{{ .LastDuration }}</td></tr>{{ end }}
+  <tr><th>Previous Messages:</th><td>
+    <div class="message-filter">
+      <input type="search" id="message-filter-text" placeholder="Filter messages" oninput="filterMessages()">
+      <select id="message-filter-status" onchange="filterMessages()">
+        <option value="">All statuses</option>
+        <option value="red">Red</option>
+        <option value="noUpdate">No update</option>
+        <option value="amber">Amber</option>
+        <option value="grey">Grey</option>
+        <option value="green">Green</option>
+      </select>
+    </div>
+    <ul class="previousMessages">{{ range $m := .Messages }}<li data-status="{{ $m.Status }}">{{ $m.TimeStamp.Format "2006-01-02T15:04:05.000Z07:00" }}: {{ $m.Status | ToUpper }} ({{ $m.Message }})</li>{{ end }}</ul>
+  </td></tr>
 
 </div>
 {{ end }}`
 
+const boxHistory = `
+{{ define "boxHistory" }}
+<div class="fullwidth history box">
+  <table>
+  <tr><th>In this state for:</th><td class="timeInState" data-since="{{ if not .Since.IsZero }}{{ .Since.Format "2006-01-02T15:04:05.000Z07:00" }}{{ end }}">{{ if .Since.IsZero }}unknown{{ else }}{{ .InState }}{{ end }}</td></tr>
+  <tr><th>Last changed:</th><td class="lastChange">{{ if .Since.IsZero }}unknown{{ else }}{{ .Since.Format "2006-01-02T15:04:05.000Z07:00" }}{{ end }}</td></tr>
+  </table>
+  {{ range .Timelines }}
+  <p class="timeline-label">{{ .Label }}</p>
+  <div class="timeline">{{ range .Segments }}<div class="segment {{ if .Status }}{{ .Status }}{{ else }}unknown{{ end }}" style="width: {{ printf "%.3f" .Percent }}%" title="{{ if .Status }}{{ .Status | ToUpper }}{{ else }}UNKNOWN{{ end }}: {{ .Start.Format "2006-01-02T15:04:05Z07:00" }} to {{ .End.Format "2006-01-02T15:04:05Z07:00" }}"></div>{{ end }}</div>
+  {{ end }}
+</div>
+{{ end }}`
+
 var templates *template.Template
 
 func loadTemplates() (err error) {
 	funcMap := template.FuncMap{
-		"ToUpper": strings.ToUpper,
+		"ToUpper":       strings.ToUpper,
+		"Markdown":      renderMarkdown,
+		"FormatMetric":  formatMetric,
+		"Sparkline":     sparkline,
+		"TileSparkline": tileSparkline,
 	}
 
 	// Start with base template and func map
 	root := template.New("root").Funcs(funcMap)
 
 	// Parse all template strings into a single tree
-	templates, err = root.Parse(generic + boxGrid + boxInfo + dashboard + infoPage)
+	templates, err = root.Parse(generic + boxGrid + boxInfo + boxHistory + dashboard + infoPage + dashboardList)
 	return err
 }
 
-func handleRoot(w http.ResponseWriter, _ *http.Request) {
-	// Get all boxes from store (thread-safe)
-	boxes := boxStore.GetAll()
-	err := templates.ExecuteTemplate(w, "dashboard", boxes)
+// pageData is what every page is rendered with.
+type pageData struct {
+	Title string
+	Theme string
+	// Kiosk mode for wall displays, set with ?kiosk=1.
+	Kiosk bool
+	// Status counts for the status bar, on pages showing boxes.
+	Summary *statusSummary
+	// Whether the layout can be edited from the dashboard.
+	Editable bool
+}
+
+func newPageData(r *http.Request, title, theme string) pageData {
+	kiosk, _ := strconv.ParseBool(r.URL.Query().Get("kiosk"))
+	return pageData{Title: title, Theme: pageTheme(r, theme), Kiosk: kiosk, Editable: options.LayoutEditor}
+}
+
+// dashboardData is what the dashboard template is rendered with.
+type dashboardData struct {
+	pageData
+	Sections []boxSection
+}
+
+type infoPageData struct {
+	pageData
+	Box *api.Box
+	// When the box changed to its current status, zero if not known, and
+	// how long ago that was.
+	Since     time.Time
+	InState   string
+	Timelines []timeline
+}
+
+type dashboardListData struct {
+	pageData
+	Dashboards []api.Dashboard
+	Themes     []string
+}
+
+// handleRoot shows every box if no dashboards have been defined, otherwise the
+// default dashboard or a list of the dashboards.
+func handleRoot(w http.ResponseWriter, r *http.Request) {
+	dashboards := dashboardStore.GetAll()
+	if len(dashboards) == 0 {
+		// Get all boxes from store (thread-safe)
+		boxes := boxStore.GetAll()
+		data := dashboardData{
+			pageData: newPageData(r, "", ""),
+			Sections: boxSections(boxes, layoutStore.Groups()),
+		}
+		data.Summary = summariseStatuses(boxes)
+		err := templates.ExecuteTemplate(w, "dashboard", data)
+		if err != nil {
+			logger.Error(err.Error())
+		}
+		return
+	}
+
+	if d, ok := dashboardStore.Default(); ok {
+		target := url.URL{Path: "/d/" + d.Slug, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, target.String(), http.StatusFound)
+		return
+	}
+
+	data := dashboardListData{
+		pageData:   newPageData(r, "Dashboards", ""),
+		Dashboards: dashboards,
+		Themes:     availableThemes(),
+	}
+	err := templates.ExecuteTemplate(w, "dashboardList", data)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	d, ok := dashboardStore.Get(chi.URLParam(r, "slug"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	boxes, groups := dashboardBoxes(d)
+	data := dashboardData{
+		pageData: newPageData(r, d.Title, d.Theme),
+		Sections: boxSections(boxes, groups),
+	}
+	data.Summary = summariseStatuses(boxes)
+	err := templates.ExecuteTemplate(w, "dashboard", data)
 	if err != nil {
 		logger.Error(err.Error())
 	}
@@ -139,7 +333,17 @@ func handleBox(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = templates.ExecuteTemplate(w, "infoPage", box)
+	now := time.Now()
+	data := infoPageData{
+		pageData:  newPageData(r, box.Name, ""),
+		Box:       box,
+		Since:     statusSince(box),
+		Timelines: boxTimelines(box, now),
+	}
+	if !data.Since.IsZero() {
+		data.InState = now.Sub(data.Since).Round(time.Second).String()
+	}
+	err = templates.ExecuteTemplate(w, "infoPage", data)
 	if err != nil {
 		logger.Error(err.Error())
 	}
@@ -156,9 +360,21 @@ func runDashboard(_ context.Context) {
 	}
 	r := chi.NewRouter()
 	r.HandleFunc("/box/{id}", handleBox)
+	r.HandleFunc("/d/{slug}", handleDashboard)
 	http.Handle("/box/", r)
+	http.Handle("/d/", r)
 	http.HandleFunc("/", handleRoot)
 
+	// Apart from acknowledging boxes over /ws, the dashboard port doesn't
+	// change anything. The layout editor saves through here so is only
+	// served when it is enabled.
+	if options.LayoutEditor {
+		r.Put("/api/v1/layout", apiPutLayout)
+		r.Put("/api/v1/dashboards/{slug}/layout", apiPutDashboardLayout)
+		http.Handle("/api/v1/layout", r)
+		http.Handle("/api/v1/dashboards/", r)
+	}
+
 	http.HandleFunc("/health", handleStatus)
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir(options.StaticPath))))
 
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"path/filepath"
+	"slices"
+
+	"github.com/baelish/alive/api"
+
+	"go.uber.org/zap"
+)
+
+// DashboardStore provides thread-safe access to the dashboards. Only one
+// dashboard can be the default.
+type DashboardStore struct {
+	namedStore[api.Dashboard]
+}
+
+// Global dashboard store instance
+var dashboardStore = &DashboardStore{namedStore[api.Dashboard]{
+	items: make(map[string]api.Dashboard),
+	name:  func(d api.Dashboard) string { return d.Slug },
+	save:  saveDashboardFile,
+}}
+
+var dashboardFile string
+
+// Default returns the default dashboard, if there is one (thread-safe read)
+func (ds *DashboardStore) Default() (api.Dashboard, bool) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	for _, d := range ds.items {
+		if d.Default {
+			return d, true
+		}
+	}
+	return api.Dashboard{}, false
+}
+
+// putDashboard adds a dashboard to dashboards, taking the default from the
+// others if it is the default.
+func putDashboard(dashboards map[string]api.Dashboard, d api.Dashboard) {
+	if d.Default {
+		for slug, other := range dashboards {
+			other.Default = false
+			dashboards[slug] = other
+		}
+	}
+	dashboards[d.Slug] = d
+}
+
+// Put adds or replaces a dashboard, returning true if it replaced one
+// (thread-safe write)
+func (ds *DashboardStore) Put(d api.Dashboard) (replaced bool, err error) {
+	err = ds.change(func(dashboards map[string]api.Dashboard) error {
+		_, replaced = dashboards[d.Slug]
+		putDashboard(dashboards, d)
+		return nil
+	})
+	return replaced, err
+}
+
+// PutIfAbsent adds a dashboard, failing with errNameExists if there is one
+// with its slug already (thread-safe write)
+func (ds *DashboardStore) PutIfAbsent(d api.Dashboard) error {
+	return ds.change(func(dashboards map[string]api.Dashboard) error {
+		if _, exists := dashboards[d.Slug]; exists {
+			return errNameExists
+		}
+		putDashboard(dashboards, d)
+		return nil
+	})
+}
+
+// Update modifies an existing dashboard (thread-safe write)
+func (ds *DashboardStore) Update(slug string, updateFn func(*api.Dashboard)) (found bool, err error) {
+	err = ds.change(func(dashboards map[string]api.Dashboard) error {
+		d, ok := dashboards[slug]
+		if found = ok; !found {
+			return errUnchanged
+		}
+		updateFn(&d)
+		putDashboard(dashboards, d)
+		return nil
+	})
+	return found, err
+}
+
+func getDashboardsFromDataFile() {
+	dashboardFile = filepath.Clean(options.DataPath + "/dashboards.json")
+
+	var loaded []api.Dashboard
+	if err := loadJSONFile(dashboardFile, &loaded); err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	dashboardStore.Set(loaded)
+}
+
+func saveDashboardFile(dashboards []api.Dashboard) error {
+	if dashboardFile == "" {
+		return nil
+	}
+
+	return saveJSONFile(dashboardFile, dashboards)
+}
+
+// dashboardBoxes returns the boxes shown on a dashboard, in order, and the
+// groups they are shown in.
+func dashboardBoxes(d api.Dashboard) ([]api.Box, []api.Group) {
+	var boxes []api.Box
+	boxStore.ForEach(func(box api.Box) bool {
+		if d.Shows(box) {
+			boxes = append(boxes, box)
+		}
+		return true // continue
+	})
+
+	groups := layoutStore.Groups()
+	if d.Layout == nil {
+		return boxes, groups
+	}
+
+	if d.Layout.Groups != nil {
+		groups = d.Layout.Groups
+	}
+	placements := make(map[string]api.Placement, len(d.Layout.Boxes))
+	for _, p := range d.Layout.Boxes {
+		placements[p.ID] = p
+	}
+	for i := range boxes {
+		if p, ok := placements[boxes[i].ID]; ok {
+			boxes[i].Group = p.Group
+			boxes[i].Order = p.Order
+		}
+	}
+	by(layoutLess(groupIndexes(groups))).Sort(boxes)
+
+	return boxes, groups
+}
+
+// setDashboardLayout changes the layout of a dashboard, starting from the
+// dashboard layout if it doesn't have its own. The groups are replaced if
+// given and the boxes given are moved.
+func setDashboardLayout(slug string, layout api.Layout) (api.Layout, bool, error) {
+	var result api.Layout
+	found, err := dashboardStore.Update(slug, func(d *api.Dashboard) {
+		// Copies of the dashboard may be being rendered, so build a new
+		// layout rather than changing the one it has.
+		result = api.Layout{Groups: layoutStore.Groups()}
+		if d.Layout != nil {
+			result.Groups = slices.Clone(d.Layout.Groups)
+			result.Boxes = slices.Clone(d.Layout.Boxes)
+		}
+		if layout.Groups != nil {
+			result.Groups = layout.Groups
+		}
+
+		placements := make(map[string]int, len(result.Boxes))
+		for i, p := range result.Boxes {
+			placements[p.ID] = i
+		}
+		for _, p := range layout.Boxes {
+			if i, ok := placements[p.ID]; ok {
+				result.Boxes[i] = p
+			} else {
+				result.Boxes = append(result.Boxes, p)
+			}
+		}
+
+		d.Layout = &result
+	})
+	if !found || err != nil {
+		return result, found, err
+	}
+
+	logger.Info("dashboard layout changed", zap.String("dashboard", slug))
+	events.messages <- resyncMessage
+
+	return result, true, nil
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/baelish/alive/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDashboards(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	originalDashboards := dashboardStore.GetAll()
+	originalDashboardFile := dashboardFile
+	originalDataPath := options.DataPath
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+		dashboardStore.Set(originalDashboards)
+		dashboardFile = originalDashboardFile
+		options.DataPath = originalDataPath
+	}()
+
+	if err := loadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	resetBoxStore()
+	options.DataPath = t.TempDir()
+	getDashboardsFromDataFile()
+
+	boxStore.Add(api.Box{ID: "ops-web", Name: "ops web", Labels: map[string]string{"team": "ops"}})
+	boxStore.Add(api.Box{ID: "ops-db", Name: "ops db", Labels: map[string]string{"team": "ops"}})
+	boxStore.Add(api.Box{ID: "dev-web", Name: "dev web", Labels: map[string]string{"team": "dev"}})
+
+	router := chi.NewRouter()
+	router.HandleFunc("/", handleRoot)
+	router.HandleFunc("/d/{slug}", handleDashboard)
+	router.Post("/api/v1/dashboards", apiCreateDashboard)
+	router.Put("/api/v1/dashboards/{slug}", apiReplaceDashboard)
+	router.Put("/api/v1/dashboards/{slug}/layout", apiPutDashboardLayout)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	// With no dashboards every box is shown.
+	rec := do("GET", "/", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "id='dev-web'") {
+		t.Errorf("expected every box at /, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "data-box-grid") {
+		t.Error("expected / to be marked as showing boxes")
+	}
+
+	if rec := do("POST", "/api/v1/dashboards", `{"slug": "noc", "title": "NOC wall", "selector": {"team": "ops"}}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create dashboard: expected %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body)
+	}
+	if rec := do("POST", "/api/v1/dashboards", `{"slug": "noc"}`); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate dashboard: expected %d, got %d", http.StatusConflict, rec.Code)
+	}
+
+	rec = do("GET", "/d/noc", "")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "id='ops-web'") || strings.Contains(body, "id='dev-web'") {
+		t.Errorf("expected only ops boxes on /d/noc, got %d: %s", rec.Code, body)
+	}
+	if !strings.Contains(body, "<title>NOC wall</title>") {
+		t.Error("expected the dashboard title")
+	}
+
+	if !strings.Contains(body, "data-health='grey'") || !strings.Contains(body, ">2 grey</span>") {
+		t.Errorf("expected the status bar to count the dashboard's boxes, got %s", body)
+	}
+	if strings.Contains(body, "class='kiosk'") {
+		t.Error("expected no kiosk mode without ?kiosk")
+	}
+	if body := do("GET", "/d/noc?kiosk=1", "").Body.String(); !strings.Contains(body, "<body class='kiosk' ") {
+		t.Errorf("expected kiosk mode with ?kiosk=1, got %s", body)
+	}
+
+	if rec := do("GET", "/d/nope", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown dashboard: expected %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	// Without a default the dashboards are listed.
+	rec = do("GET", "/", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "href='/d/noc'") {
+		t.Errorf("expected a list of dashboards, got %d: %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "data-box-grid") {
+		t.Error("expected the list of dashboards not to be marked as showing boxes")
+	}
+
+	if rec := do("PUT", "/api/v1/dashboards/noc", `{"title": "NOC wall", "selector": {"team": "ops"}, "default": true}`); rec.Code != http.StatusOK {
+		t.Fatalf("replace dashboard: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+	rec = do("GET", "/?theme=dark", "")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/d/noc?theme=dark" {
+		t.Errorf("expected a redirect to the default dashboard, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// The dashboard's own layout doesn't change the boxes.
+	rec = do("PUT", "/api/v1/dashboards/noc/layout", `{"groups": [{"id": "dbs", "title": "Databases"}], "boxes": [{"id": "ops-db", "group": "dbs", "order": 1}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("dashboard layout: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+	body = do("GET", "/d/noc", "").Body.String()
+	if !strings.Contains(body, "Databases") || strings.Index(body, "id='ops-db'") < strings.Index(body, "id='ops-web'") {
+		t.Errorf("expected ops-db in the Databases section after ops-web, got %s", body)
+	}
+	if box, _ := boxStore.GetByID("ops-db"); box.Group != "" {
+		t.Errorf("expected the box group to be left alone, got %q", box.Group)
+	}
+	if rec := do("PUT", "/api/v1/dashboards/nope/layout", `{}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown dashboard layout: expected %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	// Dashboards are persisted.
+	dashboardStore.Set(nil)
+	getDashboardsFromDataFile()
+	if d, ok := dashboardStore.Default(); !ok || d.Slug != "noc" || d.Layout == nil {
+		t.Errorf("expected dashboards to be reloaded from file, got %+v", d)
+	}
+}
+
+func TestDashboardStore_SaveFirst(t *testing.T) {
+	store := &DashboardStore{namedStore[api.Dashboard]{name: func(d api.Dashboard) string { return d.Slug }}}
+	store.Set([]api.Dashboard{{Slug: "noc", Default: true}, {Slug: "dev"}})
+
+	// Only one dashboard is the default.
+	if _, err := store.Put(api.Dashboard{Slug: "ops", Default: true}); err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := store.Default(); !ok || d.Slug != "ops" {
+		t.Fatalf("expected ops to be the default, got %+v", d)
+	}
+	if d, _ := store.Get("noc"); d.Default {
+		t.Error("expected noc to no longer be the default")
+	}
+
+	saveErr := errors.New("disk full")
+	store.save = func([]api.Dashboard) error { return saveErr }
+
+	if _, err := store.Put(api.Dashboard{Slug: "dev", Default: true}); !errors.Is(err, saveErr) {
+		t.Errorf("put: expected the save error, got %v", err)
+	}
+	if err := store.PutIfAbsent(api.Dashboard{Slug: "new"}); !errors.Is(err, saveErr) {
+		t.Errorf("put if absent: expected the save error, got %v", err)
+	}
+	if _, err := store.Update("noc", func(d *api.Dashboard) { d.Title = "changed" }); !errors.Is(err, saveErr) {
+		t.Errorf("update: expected the save error, got %v", err)
+	}
+	if _, err := store.Delete("ops"); !errors.Is(err, saveErr) {
+		t.Errorf("delete: expected the save error, got %v", err)
+	}
+
+	if d, ok := store.Default(); !ok || d.Slug != "ops" {
+		t.Errorf("expected ops to still be the default after failed saves, got %+v", d)
+	}
+	if d, _ := store.Get("noc"); d.Title != "" {
+		t.Errorf("expected noc to be unchanged after a failed save, got %+v", d)
+	}
+	if got := len(store.GetAll()); got != 3 {
+		t.Errorf("expected 3 dashboards after failed saves, got %d", got)
+	}
+}
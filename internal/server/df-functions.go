@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/baelish/alive/api"
 	"go.uber.org/zap"
@@ -69,7 +70,14 @@ func getBoxesFromDataFile() {
 }
 
 // Write json
-func saveBoxFile() error {
+func saveBoxFile() (err error) {
+	defer func(start time.Time) {
+		saveDuration.Observe(time.Since(start).Seconds())
+		if err != nil {
+			saveErrors.Inc()
+		}
+	}(time.Now())
+
 	// Get all boxes from store (thread-safe)
 	boxes := boxStore.GetAll()
 	byteValue, err := json.Marshal(&boxes)
@@ -112,3 +120,32 @@ func saveBoxFile() error {
 
 	return nil
 }
+
+// loadJSONFile reads a JSON data file into v, a missing file is not an error
+// and leaves v as it is.
+func loadJSONFile(file string, v any) error {
+	byteValue, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(byteValue, v)
+}
+
+// saveJSONFile writes v to a JSON data file, replacing it in one go so a
+// failed write doesn't leave it truncated.
+func saveJSONFile(file string, v any) error {
+	byteValue, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(file+".tmp", byteValue, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(file+".tmp", file)
+}
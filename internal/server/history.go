@@ -0,0 +1,107 @@
+package server
+
+import (
+	"time"
+
+	"github.com/baelish/alive/api"
+)
+
+const (
+	// How long status changes are kept for, the longest timeline shown on
+	// the box info page.
+	historyLength = 7 * 24 * time.Hour
+	// A limit on the changes kept for boxes which flap.
+	maxStatusChanges = 1000
+)
+
+// recordStatus adds the box's status to its history if it has changed,
+// dropping changes older than the history length.
+func recordStatus(box *api.Box, t time.Time) {
+	n := len(box.History)
+	if n > 0 && box.History[n-1].Status == box.Status {
+		return
+	}
+
+	// Keep the last change before the cut off, it tells us the status at
+	// the start of the timeline.
+	cutoff := t.Add(-historyLength)
+	start := 0
+	for start < n-1 && box.History[start+1].Time.Before(cutoff) {
+		start++
+	}
+	if n+1-start > maxStatusChanges {
+		start = n + 1 - maxStatusChanges
+	}
+
+	// Copies of the box share the old history, so build a new one.
+	history := make([]api.StatusChange, 0, n+1-start)
+	history = append(history, box.History[start:]...)
+	box.History = append(history, api.StatusChange{Status: box.Status, Time: t})
+}
+
+// statusSince returns when the box changed to its current status, zero if it
+// isn't known.
+func statusSince(box *api.Box) time.Time {
+	if n := len(box.History); n > 0 && box.History[n-1].Status == box.Status {
+		return box.History[n-1].Time
+	}
+	return time.Time{}
+}
+
+// timelineSegment is a period a box spent in one status, Status is empty when
+// it isn't known.
+type timelineSegment struct {
+	Status  string
+	Start   time.Time
+	End     time.Time
+	Percent float64
+}
+
+type timeline struct {
+	Label    string
+	Segments []timelineSegment
+}
+
+// statusTimeline splits the window up to now into the statuses the box had.
+func statusTimeline(history []api.StatusChange, window time.Duration, now time.Time) []timelineSegment {
+	from := now.Add(-window)
+	var segments []timelineSegment
+	add := func(status string, start, end time.Time) {
+		if start.Before(from) {
+			start = from
+		}
+		if !end.After(start) {
+			return
+		}
+		segments = append(segments, timelineSegment{
+			Status:  status,
+			Start:   start,
+			End:     end,
+			Percent: float64(end.Sub(start)) / float64(window) * 100,
+		})
+	}
+
+	if len(history) == 0 {
+		add("", from, now)
+		return segments
+	}
+
+	add("", from, history[0].Time)
+	for i, change := range history {
+		end := now
+		if i+1 < len(history) {
+			end = history[i+1].Time
+		}
+		add(change.Status.String(), change.Time, end)
+	}
+
+	return segments
+}
+
+// boxTimelines returns the timelines shown on the box info page.
+func boxTimelines(box *api.Box, now time.Time) []timeline {
+	return []timeline{
+		{Label: "Last 24 hours", Segments: statusTimeline(box.History, 24*time.Hour, now)},
+		{Label: "Last 7 days", Segments: statusTimeline(box.History, historyLength, now)},
+	}
+}
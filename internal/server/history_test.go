@@ -0,0 +1,172 @@
+package server
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baelish/alive/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRecordStatus(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	t.Run("only changes are recorded", func(t *testing.T) {
+		box := api.Box{Status: api.Green}
+		recordStatus(&box, now.Add(-2*time.Hour))
+		recordStatus(&box, now.Add(-time.Hour))
+		box.Status = api.Red
+		recordStatus(&box, now)
+
+		expect := []api.StatusChange{
+			{Status: api.Green, Time: now.Add(-2 * time.Hour)},
+			{Status: api.Red, Time: now},
+		}
+		expectEqual(t, box.History, expect)
+		if since := statusSince(&box); !since.Equal(now) {
+			t.Errorf("expected status since %s, got %s", now, since)
+		}
+	})
+
+	t.Run("old changes are dropped", func(t *testing.T) {
+		box := api.Box{
+			Status: api.Green,
+			History: []api.StatusChange{
+				{Status: api.Grey, Time: now.Add(-10 * 24 * time.Hour)},
+				{Status: api.Red, Time: now.Add(-9 * 24 * time.Hour)},
+				{Status: api.Amber, Time: now.Add(-24 * time.Hour)},
+			},
+		}
+		recordStatus(&box, now)
+
+		// The change before the cut off is kept as the starting status.
+		expect := []api.StatusChange{
+			{Status: api.Red, Time: now.Add(-9 * 24 * time.Hour)},
+			{Status: api.Amber, Time: now.Add(-24 * time.Hour)},
+			{Status: api.Green, Time: now},
+		}
+		expectEqual(t, box.History, expect)
+	})
+
+	t.Run("changes are limited", func(t *testing.T) {
+		box := api.Box{}
+		for i := range maxStatusChanges + 10 {
+			box.Status = api.Status(i % 2)
+			recordStatus(&box, now.Add(time.Duration(i)*time.Second))
+		}
+		if len(box.History) != maxStatusChanges {
+			t.Errorf("expected %d changes, got %d", maxStatusChanges, len(box.History))
+		}
+		if last := box.History[len(box.History)-1]; last.Status != box.Status {
+			t.Errorf("expected the latest change to be kept, got %+v", last)
+		}
+	})
+
+	t.Run("unknown without history", func(t *testing.T) {
+		box := api.Box{Status: api.Green, History: []api.StatusChange{{Status: api.Red, Time: now}}}
+		if since := statusSince(&box); !since.IsZero() {
+			t.Errorf("expected an unknown status since, got %s", since)
+		}
+	})
+}
+
+func TestStatusTimeline(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	type segment struct {
+		status  string
+		percent float64
+	}
+
+	tests := []struct {
+		name    string
+		history []api.StatusChange
+		expect  []segment
+	}{
+		{
+			name:   "no history",
+			expect: []segment{{"", 100}},
+		},
+		{
+			name: "history starts inside the window",
+			history: []api.StatusChange{
+				{Status: api.Green, Time: now.Add(-6 * time.Hour)},
+				{Status: api.Red, Time: now.Add(-3 * time.Hour)},
+			},
+			expect: []segment{{"", 75}, {"green", 12.5}, {"red", 12.5}},
+		},
+		{
+			name: "history starts before the window",
+			history: []api.StatusChange{
+				{Status: api.Grey, Time: now.Add(-48 * time.Hour)},
+				{Status: api.Amber, Time: now.Add(-30 * time.Hour)},
+				{Status: api.Green, Time: now.Add(-12 * time.Hour)},
+			},
+			expect: []segment{{"amber", 50}, {"green", 50}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []segment
+			for _, s := range statusTimeline(tt.history, 24*time.Hour, now) {
+				got = append(got, segment{s.Status, s.Percent})
+			}
+			expectEqual(t, got, tt.expect)
+		})
+	}
+}
+
+func TestHandleBox_History(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+	}()
+
+	if err := loadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	resetBoxStore()
+	redSize := 100.0
+	box := api.Box{
+		ID:          "history",
+		Name:        "History",
+		Status:      api.Green,
+		Description: "Nightly *backup*",
+		Runbook:     "https://wiki.example.com/backups",
+		Thresholds:  []api.Threshold{{Metric: "size", Red: &redSize}},
+	}
+	if _, err := addBox(box); err != nil {
+		t.Fatal(err)
+	}
+	if err := update(api.Event{ID: "history", Status: api.Red, Message: "down", Metrics: []api.MetricValue{{Name: "size", Value: 142, Unit: "GB"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := chi.NewRouter()
+	router.HandleFunc("/box/{id}", handleBox)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/box/history", nil))
+	body := rec.Body.String()
+
+	for _, expect := range []string{
+		`class="timeInState" data-since="`,
+		`<p class="timeline-label">Last 24 hours</p>`,
+		`<div class="segment red" style="width: `,
+		`<li data-status="red">`,
+		`<td class="description"><p>Nightly <em>backup</em></p>`,
+		`<a class="runbook" href="https://wiki.example.com/backups"`,
+		`<td class="metric-value" data-metric="size">142 GB</td>`,
+		`<li>size at or above 100 is red</li>`,
+	} {
+		if !strings.Contains(body, expect) {
+			t.Errorf("expected the info page to contain %q, got %s", expect, body)
+		}
+	}
+}
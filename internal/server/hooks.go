@@ -0,0 +1,237 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/baelish/alive/api"
+)
+
+// HookStore provides thread-safe access to inbound hooks
+type HookStore = namedStore[api.Hook]
+
+// Global hook store instance
+var hookStore = newNamedStore(func(h api.Hook) string { return h.Name }, saveHookFile)
+
+var hookFile string
+
+func getHooksFromDataFile() {
+	hookFile = filepath.Clean(options.DataPath + "/hooks.json")
+
+	var loaded []api.Hook
+	if err := loadJSONFile(hookFile, &loaded); err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	hookStore.Set(loaded)
+}
+
+func saveHookFile(hooks []api.Hook) error {
+	if hookFile == "" {
+		return nil
+	}
+
+	return saveJSONFile(hookFile, hooks)
+}
+
+// hookExpression extracts a value from the JSON posted to a hook, using a
+// JSONPath, a template or, for plain text, the text itself.
+type hookExpression struct {
+	text string
+	path []any
+	tmpl *template.Template
+}
+
+func parseHookExpression(s string) (hookExpression, error) {
+	e := hookExpression{text: s}
+
+	var err error
+	switch {
+	case strings.HasPrefix(s, "$"):
+		e.path, err = parseJSONPath(s)
+	case strings.Contains(s, "{{"):
+		e.tmpl, err = template.New("hook").Option("missingkey=error").Parse(s)
+	}
+
+	return e, err
+}
+
+func (e hookExpression) evaluate(data any) (string, error) {
+	switch {
+	case e.path != nil:
+		v, err := lookupJSONPath(data, e.path)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", e.text, err)
+		}
+		return hookValueString(v), nil
+	case e.tmpl != nil:
+		var b strings.Builder
+		if err := e.tmpl.Execute(&b, data); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+
+	return e.text, nil
+}
+
+// parseJSONPath parses the part of JSONPath hooks support, $ followed by
+// .key, ['key'] and [index] steps. Keys are strings and indexes ints.
+func parseJSONPath(path string) ([]any, error) {
+	steps := []any{}
+	rest := strings.TrimPrefix(path, "$")
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("invalid path %q: empty key", path)
+			}
+			steps = append(steps, key)
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "['") || strings.HasPrefix(rest, `["`):
+			end := strings.Index(rest[2:], string(rest[1])+"]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unterminated key", path)
+			}
+			steps = append(steps, rest[2:end+2])
+			rest = rest[end+4:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unterminated index", path)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid path %q: bad index %q", path, rest[1:end])
+			}
+			steps = append(steps, i)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", path, rest)
+		}
+	}
+
+	return steps, nil
+}
+
+func lookupJSONPath(data any, steps []any) (any, error) {
+	v := data
+	for _, step := range steps {
+		switch step := step.(type) {
+		case string:
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("no key %q in a non object", step)
+			}
+			if v, ok = m[step]; !ok {
+				return nil, fmt.Errorf("no key %q", step)
+			}
+		case int:
+			a, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("no index %d in a non array", step)
+			}
+			if step >= len(a) {
+				return nil, fmt.Errorf("index %d out of range", step)
+			}
+			v = a[step]
+		}
+	}
+
+	return v, nil
+}
+
+// hookValueString returns a value found by a JSONPath as text, objects and
+// arrays as JSON.
+func hookValueString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// compiledHook is a hook with its expressions parsed.
+type compiledHook struct {
+	hook                api.Hook
+	id, status, message hookExpression
+}
+
+func compileHook(h api.Hook) (compiledHook, error) {
+	c := compiledHook{hook: h}
+
+	var err error
+	if c.id, err = parseHookExpression(h.ID); err != nil {
+		return c, fmt.Errorf("invalid id: %w", err)
+	}
+	if c.status, err = parseHookExpression(h.Status); err != nil {
+		return c, fmt.Errorf("invalid status: %w", err)
+	}
+	if c.message, err = parseHookExpression(h.Message); err != nil {
+		return c, fmt.Errorf("invalid message: %w", err)
+	}
+
+	return c, nil
+}
+
+// event builds the event for the JSON posted to the hook.
+func (c compiledHook) event(data any) (api.Event, error) {
+	var event api.Event
+
+	id, err := c.id.evaluate(data)
+	if err != nil {
+		return event, fmt.Errorf("could not get id: %w", err)
+	}
+	if id == "" {
+		return event, fmt.Errorf("could not get id: %s is empty", c.hook.ID)
+	}
+
+	value, err := c.status.evaluate(data)
+	if err != nil {
+		return event, fmt.Errorf("could not get status: %w", err)
+	}
+	status, err := hookStatus(c.hook, value)
+	if err != nil {
+		return event, err
+	}
+
+	message, err := c.message.evaluate(data)
+	if err != nil {
+		return event, fmt.Errorf("could not get message: %w", err)
+	}
+
+	return api.Event{ID: id, Status: status, Message: message}, nil
+}
+
+// hookStatus maps a value found by a hook to a status.
+func hookStatus(h api.Hook, value string) (api.Status, error) {
+	if s, ok := h.StatusMap[value]; ok {
+		return s, nil
+	}
+	if s, ok := h.StatusMap["*"]; ok {
+		return s, nil
+	}
+
+	var s api.Status
+	if err := s.UnmarshalJSON([]byte(strconv.Quote(value))); err != nil {
+		return s, fmt.Errorf("no status for %q", value)
+	}
+	return s, nil
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/baelish/alive/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		expect      []any
+		expectError bool
+	}{
+		{path: "$", expect: []any{}},
+		{path: "$.build.status", expect: []any{"build", "status"}},
+		{path: "$.checks[2].name", expect: []any{"checks", 2, "name"}},
+		{path: "$['monitor name'].state", expect: []any{"monitor name", "state"}},
+		{path: `$["a.b"][0]`, expect: []any{"a.b", 0}},
+		{path: "$..status", expectError: true},
+		{path: "$.checks[x]", expectError: true},
+		{path: "$.checks[-1]", expectError: true},
+		{path: "$['name", expectError: true},
+		{path: "$status", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parseJSONPath(tt.path)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if !tt.expectError && !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected %#v, got %#v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestHookEvent(t *testing.T) {
+	payload := `{
+		"repository": {"name": "alive"},
+		"build": {"number": 42, "result": "FAILURE", "passed": false},
+		"stages": [{"name": "test", "log": "2 tests failed"}]
+	}`
+
+	tests := []struct {
+		name        string
+		hook        api.Hook
+		expect      api.Event
+		expectError bool
+	}{
+		{
+			name: "json paths and status map",
+			hook: api.Hook{
+				Name:      "ci",
+				ID:        "$.repository.name",
+				Status:    "$.build.result",
+				Message:   "$.stages[0].log",
+				StatusMap: map[string]api.Status{"SUCCESS": api.Green, "FAILURE": api.Red, "*": api.Amber},
+			},
+			expect: api.Event{ID: "alive", Status: api.Red, Message: "2 tests failed"},
+		},
+		{
+			name: "templates",
+			hook: api.Hook{
+				Name:      "ci",
+				ID:        "ci-{{.repository.name}}",
+				Status:    "{{.build.passed}}",
+				Message:   "build {{.build.number}} {{.build.result}}",
+				StatusMap: map[string]api.Status{"true": api.Green, "false": api.Red},
+			},
+			expect: api.Event{ID: "ci-alive", Status: api.Red, Message: "build 42 FAILURE"},
+		},
+		{
+			name: "fallback status",
+			hook: api.Hook{
+				Name:      "ci",
+				ID:        "ci",
+				Status:    "$.build.result",
+				StatusMap: map[string]api.Status{"SUCCESS": api.Green, "*": api.Amber},
+			},
+			expect: api.Event{ID: "ci", Status: api.Amber},
+		},
+		{
+			name:   "status names",
+			hook:   api.Hook{Name: "ci", ID: "ci", Status: "red"},
+			expect: api.Event{ID: "ci", Status: api.Red},
+		},
+		{
+			name:        "unmapped status",
+			hook:        api.Hook{Name: "ci", ID: "ci", Status: "$.build.result"},
+			expectError: true,
+		},
+		{
+			name:        "missing id",
+			hook:        api.Hook{Name: "ci", ID: "$.repo.name", Status: "red"},
+			expectError: true,
+		},
+		{
+			name:        "missing template key",
+			hook:        api.Hook{Name: "ci", ID: "ci", Status: "red", Message: "{{.build.url}}"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data any
+			decoder := json.NewDecoder(strings.NewReader(payload))
+			decoder.UseNumber()
+			if err := decoder.Decode(&data); err != nil {
+				t.Fatal(err)
+			}
+
+			hook, err := compileHook(tt.hook)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := hook.event(data)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if !tt.expectError && !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected %+v, got %+v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestHookAPI(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	originalHooks := hookStore.GetAll()
+	originalHookFile := hookFile
+	originalDataPath := options.DataPath
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+		hookStore.Set(originalHooks)
+		hookFile = originalHookFile
+		options.DataPath = originalDataPath
+	}()
+
+	resetBoxStore()
+	options.DataPath = t.TempDir()
+	getHooksFromDataFile()
+	boxStore.Add(api.Box{ID: "backup-db1", Status: api.Grey})
+
+	router := chi.NewRouter()
+	router.Post("/api/v1/hooks", apiCreateHook)
+	router.Put("/api/v1/hooks/{name}", apiReplaceHook)
+	router.Delete("/api/v1/hooks/{name}", apiDeleteHook)
+	router.Post("/api/v1/hooks/{name}", apiReceiveHook)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	hook := `{"name": "backup", "id": "backup-{{.host}}", "status": "$.result", "message": "$.summary", "statusMap": {"ok": "green", "warning": "amber", "*": "red"}}`
+	if rec := do("POST", "/api/v1/hooks", hook); rec.Code != http.StatusCreated {
+		t.Fatalf("create hook: expected %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body)
+	}
+	if rec := do("POST", "/api/v1/hooks", hook); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate hook: expected %d, got %d", http.StatusConflict, rec.Code)
+	}
+	if rec := do("POST", "/api/v1/hooks", `{"name": "bad", "id": "$..x", "status": "red"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid path: expected %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := do("POST", "/api/v1/hooks", `{"name": "bad", "id": "x", "status": "red", "statusMap": {"ok": "blue"}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid status map: expected %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := do("PUT", "/api/v1/hooks/other", hook); rec.Code != http.StatusBadRequest {
+		t.Errorf("name mismatch: expected %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec := do("POST", "/api/v1/hooks/backup", `{"host": "db1", "result": "failed", "summary": "disk full"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("receive: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+	box, err := boxStore.GetByID("backup-db1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if box.Status != api.Red || box.LastMessage != "disk full" {
+		t.Errorf("expected box red with message, got %s %q", box.Status, box.LastMessage)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		expectCode int
+	}{
+		{"unknown hook", "/api/v1/hooks/other", `{}`, http.StatusNotFound},
+		{"unknown box", "/api/v1/hooks/backup", `{"host": "db2", "result": "ok", "summary": ""}`, http.StatusNotFound},
+		{"missing field", "/api/v1/hooks/backup", `{"host": "db1", "summary": ""}`, http.StatusUnprocessableEntity},
+		{"invalid json", "/api/v1/hooks/backup", `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do("POST", tt.path, tt.body); rec.Code != tt.expectCode {
+				t.Errorf("expected %d, got %d: %s", tt.expectCode, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	// Hooks are saved and reloaded.
+	hookStore.Set(nil)
+	getHooksFromDataFile()
+	if got, ok := hookStore.Get("backup"); !ok || got.StatusMap["warning"] != api.Amber {
+		t.Errorf("expected hook to be reloaded from file, got %+v", got)
+	}
+
+	if rec := do("DELETE", "/api/v1/hooks/backup", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete: expected %d, got %d", http.StatusNoContent, rec.Code)
+	}
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"math"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/baelish/alive/api"
+
+	"go.uber.org/zap"
+)
+
+// LayoutStore provides thread-safe access to the dashboard groups. It must not
+// call into the box store while holding its lock, as sorting the boxes reads
+// the groups.
+type LayoutStore struct {
+	mu     sync.RWMutex
+	groups []api.Group
+	save   func([]api.Group) error
+}
+
+// Global layout store instance
+var layoutStore = &LayoutStore{save: saveLayoutFile}
+
+var layoutFile string
+
+// Groups returns a copy of the groups in order (thread-safe read)
+func (ls *LayoutStore) Groups() []api.Group {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	return slices.Clone(ls.groups)
+}
+
+// SetGroups replaces the groups (thread-safe write)
+func (ls *LayoutStore) SetGroups(groups []api.Group) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.groups = slices.Clone(groups)
+}
+
+// Replace saves the groups and only then replaces the groups with them, so a
+// failed save leaves the groups as they were (thread-safe write)
+func (ls *LayoutStore) Replace(groups []api.Group) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if ls.save != nil {
+		if err := ls.save(groups); err != nil {
+			return err
+		}
+	}
+	ls.groups = slices.Clone(groups)
+	return nil
+}
+
+// groupIndexes returns the position of each group (thread-safe read)
+func (ls *LayoutStore) groupIndexes() map[string]int {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	return groupIndexes(ls.groups)
+}
+
+// groupIndexes returns the position of each group.
+func groupIndexes(groups []api.Group) map[string]int {
+	indexes := make(map[string]int, len(groups))
+	for i, g := range groups {
+		indexes[g.ID] = i
+	}
+	return indexes
+}
+
+func getLayoutFromDataFile() {
+	layoutFile = filepath.Clean(options.DataPath + "/layout.json")
+
+	var loaded api.Layout
+	if err := loadJSONFile(layoutFile, &loaded); err != nil {
+		logger.Fatal(err.Error())
+	}
+	layoutStore.SetGroups(loaded.Groups)
+
+	boxStore.mu.Lock()
+	boxStore.sortUnsafe()
+	boxStore.mu.Unlock()
+}
+
+func saveLayoutFile(groups []api.Group) error {
+	if layoutFile == "" {
+		return nil
+	}
+
+	return saveJSONFile(layoutFile, api.Layout{Groups: groups})
+}
+
+// layoutLess returns a function ordering boxes as they are shown: ungrouped
+// boxes first, then groups in layout order (unknown groups last, by name),
+// then by order within the group (unordered boxes last), then largest first
+// and by name.
+func layoutLess(groups map[string]int) func(p1, p2 *api.Box) bool {
+	groupIndex := func(group string) int {
+		if group == "" {
+			return -1
+		}
+		if i, ok := groups[group]; ok {
+			return i
+		}
+		return len(groups)
+	}
+
+	order := func(o int) int {
+		if o == 0 {
+			return math.MaxInt
+		}
+		return o
+	}
+
+	return func(p1, p2 *api.Box) bool {
+		if g1, g2 := groupIndex(p1.Group), groupIndex(p2.Group); g1 != g2 {
+			return g1 < g2
+		}
+		if p1.Group != p2.Group {
+			return p1.Group < p2.Group
+		}
+		if o1, o2 := order(p1.Order), order(p2.Order); o1 != o2 {
+			return o1 < o2
+		}
+		if p1.Size != p2.Size {
+			return int(p1.Size) > int(p2.Size)
+		}
+		return p1.Name < p2.Name
+	}
+}
+
+// groupElementID is the ID of the heading of a group on the dashboard.
+func groupElementID(group string) string {
+	return "group-" + group
+}
+
+// precedingElementID returns the ID of the element on the dashboard a new box
+// should be placed after, given the sorted boxes and the new box's index.
+func precedingElementID(boxes []api.Box, i int) string {
+	if i > 0 && boxes[i-1].Group == boxes[i].Group {
+		return boxes[i-1].ID
+	}
+	if boxes[i].Group == "" {
+		return "status-bar"
+	}
+	return groupElementID(boxes[i].Group)
+}
+
+// boxSection is a group of boxes shown together on the dashboard.
+type boxSection struct {
+	Group api.Group
+	Boxes []api.Box
+}
+
+// boxSections splits sorted boxes into sections, ungrouped boxes first then
+// every group in the layout (even if empty) and any other groups boxes are
+// in.
+func boxSections(boxes []api.Box, groups []api.Group) []boxSection {
+	sections := []boxSection{{}}
+	index := make(map[string]int)
+	for _, g := range groups {
+		if g.Title == "" {
+			g.Title = g.ID
+		}
+		index[g.ID] = len(sections)
+		sections = append(sections, boxSection{Group: g})
+	}
+
+	for _, box := range boxes {
+		i, ok := 0, box.Group == ""
+		if !ok {
+			i, ok = index[box.Group]
+		}
+		if !ok {
+			i = len(sections)
+			index[box.Group] = i
+			sections = append(sections, boxSection{Group: api.Group{ID: box.Group, Title: box.Group}})
+		}
+		sections[i].Boxes = append(sections[i].Boxes, box)
+	}
+
+	return sections
+}
+
+// currentLayout returns the groups and where every box is placed.
+func currentLayout() api.Layout {
+	layout := api.Layout{Groups: layoutStore.Groups()}
+	boxStore.ForEach(func(box api.Box) bool {
+		layout.Boxes = append(layout.Boxes, api.Placement{ID: box.ID, Group: box.Group, Order: box.Order})
+		return true // continue
+	})
+
+	return layout
+}
+
+// setLayout replaces the groups, if given, and moves the boxes given. Boxes
+// which no longer exist are ignored.
+func setLayout(layout api.Layout) error {
+	if layout.Groups != nil {
+		if err := layoutStore.Replace(layout.Groups); err != nil {
+			return err
+		}
+	}
+
+	placements := make(map[string]api.Placement, len(layout.Boxes))
+	for _, p := range layout.Boxes {
+		placements[p.ID] = p
+	}
+
+	boxStore.mu.Lock()
+	for i := range boxStore.boxes {
+		if p, ok := placements[boxStore.boxes[i].ID]; ok {
+			boxStore.boxes[i].Group = p.Group
+			boxStore.boxes[i].Order = p.Order
+		}
+	}
+	boxStore.sortUnsafe()
+	boxStore.mu.Unlock()
+
+	logger.Info("layout changed", zap.Int("groups", len(layoutStore.Groups())), zap.Int("boxes", len(placements)))
+	events.messages <- resyncMessage
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/baelish/alive/api"
+)
+
+func boxIDs(boxes []api.Box) []string {
+	ids := make([]string, 0, len(boxes))
+	for _, box := range boxes {
+		ids = append(ids, box.ID)
+	}
+	return ids
+}
+
+func TestBoxStore_SortingByLayout(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	originalGroups := layoutStore.Groups()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+		layoutStore.SetGroups(originalGroups)
+	}()
+
+	resetBoxStore()
+	layoutStore.SetGroups([]api.Group{{ID: "web"}, {ID: "db"}})
+
+	boxStore.Add(api.Box{ID: "db-b", Name: "b", Group: "db", Size: api.Xlarge})
+	boxStore.Add(api.Box{ID: "db-a", Name: "a", Group: "db", Size: api.Small})
+	boxStore.Add(api.Box{ID: "web-2", Name: "web", Group: "web", Order: 2})
+	boxStore.Add(api.Box{ID: "web-1", Name: "web", Group: "web", Order: 1})
+	boxStore.Add(api.Box{ID: "web-unordered", Name: "a", Group: "web", Size: api.Xlarge})
+	boxStore.Add(api.Box{ID: "other", Name: "other", Group: "zzz"})
+	boxStore.Add(api.Box{ID: "ungrouped", Name: "ungrouped", Size: api.Dot})
+
+	expect := []string{"ungrouped", "web-1", "web-2", "web-unordered", "db-b", "db-a", "other"}
+	if got := boxIDs(boxStore.GetAll()); strings.Join(got, ",") != strings.Join(expect, ",") {
+		t.Errorf("expected order %v, got %v", expect, got)
+	}
+}
+
+func TestPrecedingElementID(t *testing.T) {
+	boxes := []api.Box{
+		{ID: "a"},
+		{ID: "b"},
+		{ID: "c", Group: "web"},
+		{ID: "d", Group: "web"},
+	}
+
+	tests := []struct {
+		index  int
+		expect string
+	}{
+		{index: 0, expect: "status-bar"},
+		{index: 1, expect: "a"},
+		{index: 2, expect: "group-web"},
+		{index: 3, expect: "c"},
+	}
+
+	for _, tt := range tests {
+		if got := precedingElementID(boxes, tt.index); got != tt.expect {
+			t.Errorf("box %d: expected %q, got %q", tt.index, tt.expect, got)
+		}
+	}
+}
+
+func TestBoxSections(t *testing.T) {
+	boxes := []api.Box{
+		{ID: "a"},
+		{ID: "b", Group: "web"},
+		{ID: "c", Group: "unknown"},
+	}
+	groups := []api.Group{{ID: "web", Title: "Web servers"}, {ID: "empty"}}
+
+	sections := boxSections(boxes, groups)
+
+	expect := []struct {
+		group string
+		title string
+		boxes string
+	}{
+		{group: "", title: "", boxes: "a"},
+		{group: "web", title: "Web servers", boxes: "b"},
+		{group: "empty", title: "empty", boxes: ""},
+		{group: "unknown", title: "unknown", boxes: "c"},
+	}
+	if len(sections) != len(expect) {
+		t.Fatalf("expected %d sections, got %d", len(expect), len(sections))
+	}
+	for i, e := range expect {
+		s := sections[i]
+		if s.Group.ID != e.group || s.Group.Title != e.title || strings.Join(boxIDs(s.Boxes), ",") != e.boxes {
+			t.Errorf("section %d: expected %+v, got %+v", i, e, s)
+		}
+	}
+}
+
+func TestLayoutAPI(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	originalGroups := layoutStore.Groups()
+	originalLayoutFile := layoutFile
+	originalDataPath := options.DataPath
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+		layoutStore.SetGroups(originalGroups)
+		layoutFile = originalLayoutFile
+		options.DataPath = originalDataPath
+	}()
+
+	resetBoxStore()
+	options.DataPath = t.TempDir()
+	getLayoutFromDataFile()
+
+	boxStore.Add(api.Box{ID: "a", Name: "a"})
+	boxStore.Add(api.Box{ID: "b", Name: "b"})
+	boxStore.Add(api.Box{ID: "c", Name: "c"})
+
+	put := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		apiPutLayout(rec, httptest.NewRequest("PUT", "/api/v1/layout", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := put(`{"groups": [{"id": "top", "title": "Top"}], "boxes": [{"id": "c", "group": "top", "order": 1}, {"id": "b", "order": 1}, {"id": "a", "order": 2}, {"id": "gone", "order": 3}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+
+	var layout api.Layout
+	if err := json.NewDecoder(rec.Body).Decode(&layout); err != nil {
+		t.Fatal(err)
+	}
+	if len(layout.Groups) != 1 || layout.Groups[0].Title != "Top" {
+		t.Errorf("expected the new groups, got %+v", layout.Groups)
+	}
+	if got := strings.Join(boxIDs(boxStore.GetAll()), ","); got != "b,a,c" {
+		t.Errorf("expected boxes in order b,a,c, got %s", got)
+	}
+
+	// Groups are kept when only boxes are moved.
+	if rec := put(`{"boxes": [{"id": "a", "order": 1}, {"id": "b", "order": 2}]}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+	if got := strings.Join(boxIDs(boxStore.GetAll()), ","); got != "a,b,c" {
+		t.Errorf("expected boxes in order a,b,c, got %s", got)
+	}
+
+	if rec := put(`{"groups": [{"id": "bad id"}]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid group: expected %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	// Groups are persisted.
+	layoutStore.SetGroups(nil)
+	getLayoutFromDataFile()
+	if groups := layoutStore.Groups(); len(groups) != 1 || groups[0].ID != "top" {
+		t.Errorf("expected groups to be reloaded from file, got %+v", groups)
+	}
+}
+
+func TestLayoutEditor(t *testing.T) {
+	originalLayoutEditor := options.LayoutEditor
+	defer func() { options.LayoutEditor = originalLayoutEditor }()
+
+	if err := loadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, enabled := range []bool{false, true} {
+		options.LayoutEditor = enabled
+		rec := httptest.NewRecorder()
+		handleRoot(rec, httptest.NewRequest("GET", "/", nil))
+		if got := strings.Contains(rec.Body.String(), "edit-layout"); got != enabled {
+			t.Errorf("enable-layout-editor %v: expected edit controls %v, got %v", enabled, enabled, got)
+		}
+	}
+}
+
+func TestLayoutStore_SaveFirst(t *testing.T) {
+	saveErr := errors.New("disk full")
+	store := &LayoutStore{save: func([]api.Group) error { return saveErr }}
+	store.SetGroups([]api.Group{{ID: "top"}})
+
+	if err := store.Replace([]api.Group{{ID: "bottom"}}); !errors.Is(err, saveErr) {
+		t.Errorf("expected the save error, got %v", err)
+	}
+	if groups := store.Groups(); len(groups) != 1 || groups[0].ID != "top" {
+		t.Errorf("expected the groups to be unchanged after a failed save, got %+v", groups)
+	}
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"go.uber.org/zap"
+)
+
+var (
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+	// Box descriptions come from anyone who can use the API, so only allow
+	// what user generated content needs.
+	markdownPolicy = bluemonday.UGCPolicy().AddTargetBlankToFullyQualifiedLinks(true)
+)
+
+// renderMarkdown turns Markdown into HTML that is safe to put on a page.
+func renderMarkdown(source string) template.HTML {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		logger.Warn("failed to render markdown", zap.Error(err))
+		return template.HTML(template.HTMLEscapeString(source))
+	}
+
+	return template.HTML(markdownPolicy.SanitizeBytes(buf.Bytes()))
+}
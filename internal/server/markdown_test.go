@@ -0,0 +1,62 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "formatting",
+			source:   "Restart **carefully**, see `systemctl`",
+			contains: []string{"<strong>carefully</strong>", "<code>systemctl</code>"},
+		},
+		{
+			name:     "links open in a new tab",
+			source:   "[dashboard](https://grafana.example.com/d/db)",
+			contains: []string{`href="https://grafana.example.com/d/db"`, `target="_blank"`},
+		},
+		{
+			name:     "lists",
+			source:   "1. check disk\n2. restart",
+			contains: []string{"<ol>", "<li>check disk</li>"},
+		},
+		{
+			name:     "scripts are removed",
+			source:   "hello <script>alert(1)</script>",
+			excludes: []string{"<script", "alert(1)</script>"},
+		},
+		{
+			name:     "javascript links are removed",
+			source:   "[click](javascript:alert(1))",
+			excludes: []string{"javascript:"},
+		},
+		{
+			name:     "event handlers are removed",
+			source:   `<img src="x" onerror="alert(1)">`,
+			excludes: []string{"onerror"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(renderMarkdown(tt.source))
+			for _, c := range tt.contains {
+				if !strings.Contains(got, c) {
+					t.Errorf("expected %q in %q", c, got)
+				}
+			}
+			for _, e := range tt.excludes {
+				if strings.Contains(got, e) {
+					t.Errorf("expected no %q in %q", e, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/baelish/alive/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	eventsIngested = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alive_events_total",
+		Help: "Number of box events ingested.",
+	})
+
+	saveDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: "alive_save_duration_seconds",
+		Help: "Time taken to save the box file.",
+	})
+
+	saveErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alive_save_errors_total",
+		Help: "Number of times saving the box file failed.",
+	})
+
+	syslogMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "alive_syslog_messages_total",
+		Help: "Number of syslog messages received, by whether they matched a rule.",
+	}, []string{"result"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		eventsIngested,
+		saveDuration,
+		saveErrors,
+		syslogMessages,
+		boxCollector{},
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "alive_sse_dropped_messages_total",
+			Help: "Number of messages dropped for clients which could not keep up.",
+		}, func() float64 { return float64(events.DroppedCount()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "alive_sse_resyncs_total",
+			Help: "Number of times a client which could not keep up was told to resync.",
+		}, func() float64 { return float64(events.ResyncCount()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "alive_sse_clients",
+			Help: "Number of connected dashboard clients.",
+		}, func() float64 { return float64(events.ClientCount()) }),
+	)
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// metricLabelName turns a box label key into a valid Prometheus label name.
+func metricLabelName(key string) string {
+	return "label_" + invalidLabelChars.ReplaceAllString(key, "_")
+}
+
+const (
+	boxStatusName = "alive_box_status"
+	boxStatusHelp = "Status of the box (0 grey, 1 red, 2 amber, 3 green, 4 noUpdate)."
+)
+
+var (
+	boxSinceUpdateDesc = prometheus.NewDesc(
+		"alive_box_seconds_since_update",
+		"Seconds since the box was last updated.",
+		[]string{"id"}, nil,
+	)
+
+	boxesDesc = prometheus.NewDesc(
+		"alive_boxes",
+		"Number of boxes with each status.",
+		[]string{"status"}, nil,
+	)
+)
+
+// boxCollector exposes the state of each box when scraped. The label names
+// of alive_box_status depend on the labels the boxes have, so it is an
+// unchecked collector.
+type boxCollector struct{}
+
+func (boxCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (boxCollector) Collect(ch chan<- prometheus.Metric) {
+	boxes := boxStore.GetAll()
+
+	// Every box gets every label so they are consistent within a scrape.
+	labelKeys := make(map[string]string)
+	for _, box := range boxes {
+		for key := range box.Labels {
+			labelKeys[metricLabelName(key)] = key
+		}
+	}
+	labelNames := make([]string, 0, len(labelKeys))
+	for name := range labelKeys {
+		labelNames = append(labelNames, name)
+	}
+	sort.Strings(labelNames)
+
+	statusDesc := prometheus.NewDesc(
+		boxStatusName, boxStatusHelp,
+		append([]string{"id", "name", "parent"}, labelNames...), nil,
+	)
+
+	counts := make(map[api.Status]int)
+	for _, box := range boxes {
+		counts[box.Status]++
+
+		values := []string{box.ID, box.Name, box.Parent}
+		for _, name := range labelNames {
+			values = append(values, box.Labels[labelKeys[name]])
+		}
+		ch <- prometheus.MustNewConstMetric(statusDesc, prometheus.GaugeValue, float64(box.Status), values...)
+
+		if !box.LastUpdate.IsZero() {
+			ch <- prometheus.MustNewConstMetric(boxSinceUpdateDesc, prometheus.GaugeValue, time.Since(box.LastUpdate).Seconds(), box.ID)
+		}
+	}
+
+	for _, status := range []api.Status{api.Grey, api.Red, api.Amber, api.Green, api.NoUpdate} {
+		ch <- prometheus.MustNewConstMetric(boxesDesc, prometheus.GaugeValue, float64(counts[status]), status.String())
+	}
+}
+
+func metricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baelish/alive/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestMetricLabelName(t *testing.T) {
+	tests := map[string]string{
+		"team":                   "label_team",
+		"app.kubernetes.io/name": "label_app_kubernetes_io_name",
+		"9lives":                 "label_9lives",
+	}
+
+	for key, expected := range tests {
+		if got := metricLabelName(key); got != expected {
+			t.Errorf("%q: expected %q, got %q", key, expected, got)
+		}
+	}
+}
+
+func TestBoxCollector(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+	}()
+	resetBoxStore()
+
+	boxStore.Add(api.Box{ID: "web", Name: "Web", Status: api.Green, Labels: map[string]string{"team": "ops"}, LastUpdate: time.Now()})
+	boxStore.Add(api.Box{ID: "db", Name: "DB", Parent: "web", Status: api.Red})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(boxCollector{})
+
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, expected := range []string{
+		`alive_box_status{id="web",label_team="ops",name="Web",parent=""} 3`,
+		`alive_box_status{id="db",label_team="",name="DB",parent="web"} 1`,
+		`alive_box_seconds_since_update{id="web"}`,
+		`alive_boxes{status="green"} 1`,
+		`alive_boxes{status="red"} 1`,
+		`alive_boxes{status="amber"} 0`,
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", expected, body)
+		}
+	}
+
+	if strings.Contains(string(body), `alive_box_seconds_since_update{id="db"}`) {
+		t.Error("expected no time since update for a box which has never been updated")
+	}
+}
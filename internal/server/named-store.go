@@ -0,0 +1,133 @@
+package server
+
+import (
+	"errors"
+	"maps"
+	"sort"
+	"sync"
+)
+
+// namedStore provides thread-safe access to things kept by name, such as
+// templates and hooks. Changes are saved before they are made, so a failed
+// save leaves the store as it was.
+type namedStore[T any] struct {
+	mu    sync.RWMutex
+	items map[string]T
+	name  func(T) string
+	save  func([]T) error
+}
+
+var errNameExists = errors.New("the name is already used")
+
+// errUnchanged is returned by a change which left the store alone, so there
+// is nothing to save.
+var errUnchanged = errors.New("unchanged")
+
+func newNamedStore[T any](name func(T) string, save func([]T) error) *namedStore[T] {
+	return &namedStore[T]{items: make(map[string]T), name: name, save: save}
+}
+
+func sortedByName[T any](items map[string]T) []T {
+	names := make([]string, 0, len(items))
+	for name := range items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]T, 0, len(items))
+	for _, name := range names {
+		result = append(result, items[name])
+	}
+
+	return result
+}
+
+// GetAll returns everything in the store sorted by name (thread-safe read)
+func (ns *namedStore[T]) GetAll() []T {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	return sortedByName(ns.items)
+}
+
+// Get returns an item by name (thread-safe read)
+func (ns *namedStore[T]) Get(name string) (T, bool) {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	item, ok := ns.items[name]
+	return item, ok
+}
+
+// Set replaces everything in the store without saving, for loading it from
+// the data file (thread-safe write)
+func (ns *namedStore[T]) Set(items []T) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	ns.items = make(map[string]T, len(items))
+	for _, item := range items {
+		ns.items[ns.name(item)] = item
+	}
+}
+
+// change applies fn to a copy of the items, saves it and only then replaces
+// the items with it (thread-safe write)
+func (ns *namedStore[T]) change(fn func(map[string]T) error) error {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	next := maps.Clone(ns.items)
+	if next == nil {
+		next = make(map[string]T)
+	}
+	if err := fn(next); errors.Is(err, errUnchanged) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if ns.save != nil {
+		if err := ns.save(sortedByName(next)); err != nil {
+			return err
+		}
+	}
+	ns.items = next
+
+	return nil
+}
+
+// Put adds or replaces an item, returning true if it replaced one
+// (thread-safe write)
+func (ns *namedStore[T]) Put(item T) (replaced bool, err error) {
+	err = ns.change(func(items map[string]T) error {
+		_, replaced = items[ns.name(item)]
+		items[ns.name(item)] = item
+		return nil
+	})
+	return replaced, err
+}
+
+// PutIfAbsent adds an item, failing with errNameExists if there is one with
+// its name already (thread-safe write)
+func (ns *namedStore[T]) PutIfAbsent(item T) error {
+	return ns.change(func(items map[string]T) error {
+		if _, exists := items[ns.name(item)]; exists {
+			return errNameExists
+		}
+		items[ns.name(item)] = item
+		return nil
+	})
+}
+
+// Delete removes an item by name (thread-safe write)
+func (ns *namedStore[T]) Delete(name string) (found bool, err error) {
+	err = ns.change(func(items map[string]T) error {
+		if _, found = items[name]; !found {
+			return errUnchanged
+		}
+		delete(items, name)
+		return nil
+	})
+	return found, err
+}
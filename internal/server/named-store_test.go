@@ -0,0 +1,80 @@
+package server
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func newTestNamedStore(names ...string) *namedStore[string] {
+	store := newNamedStore(func(s string) string { return s }, nil)
+	store.Set(names)
+	return store
+}
+
+func TestNamedStore_SaveFirst(t *testing.T) {
+	store := newTestNamedStore("web")
+	saveErr := errors.New("disk full")
+	store.save = func([]string) error { return saveErr }
+
+	if _, err := store.Put("db"); !errors.Is(err, saveErr) {
+		t.Errorf("put: expected the save error, got %v", err)
+	}
+	if err := store.PutIfAbsent("db"); !errors.Is(err, saveErr) {
+		t.Errorf("put if absent: expected the save error, got %v", err)
+	}
+	if _, err := store.Delete("web"); !errors.Is(err, saveErr) {
+		t.Errorf("delete: expected the save error, got %v", err)
+	}
+
+	got := store.GetAll()
+	if len(got) != 1 || got[0] != "web" {
+		t.Errorf("expected the store to be unchanged after failed saves, got %+v", got)
+	}
+}
+
+func TestNamedStore_Save(t *testing.T) {
+	store := newTestNamedStore("web")
+	var saved []string
+	store.save = func(names []string) error {
+		saved = names
+		return nil
+	}
+
+	if replaced, err := store.Put("db"); err != nil || replaced {
+		t.Errorf("put: expected a new item, got replaced %v, %v", replaced, err)
+	}
+	expectEqual(t, saved, []string{"db", "web"})
+
+	saved = nil
+	if found, err := store.Delete("nope"); err != nil || found {
+		t.Errorf("delete: expected nothing to be found, got %v, %v", found, err)
+	}
+	if saved != nil {
+		t.Errorf("expected nothing to be saved when nothing changed, got %q", saved)
+	}
+}
+
+func TestNamedStore_PutIfAbsent(t *testing.T) {
+	store := newTestNamedStore()
+
+	var wg sync.WaitGroup
+	var added atomic.Int32
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.PutIfAbsent("web"); err == nil {
+				added.Add(1)
+			} else if !errors.Is(err, errNameExists) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if added.Load() != 1 {
+		t.Errorf("expected exactly one item to be added, got %d", added.Load())
+	}
+}
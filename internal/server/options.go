@@ -17,6 +17,10 @@ type Options struct {
 	ParentUrl     string `long:"parent-url" description:"Url for a parent dashboard, if set enables updating a parent dashboard with the overal status of this dashboard"`
 	ParentBoxID   string `long:"parent-id" description:"Box id to use when updating status on a parent dashboard"`
 	ParentBoxSize string `long:"parent-size" description:"Box size to use when updating status on a parent dashboard (default: large)" default:"large"`
+	ExecChecks    bool   `long:"enable-exec-checks" description:"Allow boxes to have exec checks, which run commands on this server"`
+	LayoutEditor  bool   `long:"enable-layout-editor" description:"Allow the layout to be edited from the dashboard, anyone who can see the dashboard can then change it"`
+	SyslogUDP     string `long:"syslog-udp" description:"Address to receive syslog messages on over UDP, e.g. :514 (default: disabled)"`
+	SyslogTCP     string `long:"syslog-tcp" description:"Address to receive syslog messages on over TCP, e.g. :514 (default: disabled)"`
 }
 
 var options Options
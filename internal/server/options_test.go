@@ -112,6 +112,15 @@ func TestOptions_CustomValues(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "enable exec checks",
+			args: []string{"--enable-exec-checks"},
+			validate: func(t *testing.T, opts Options) {
+				if !opts.ExecChecks {
+					t.Error("expected ExecChecks to be true")
+				}
+			},
+		},
 		{
 			name: "custom data path with short flag",
 			args: []string{"-d", "/custom/data"},
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/baelish/alive/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Only this much of a ping body is used as the message.
+const maxPingBodySize = 10 * 1024
+
+type pingKind int
+
+const (
+	pingSuccess pingKind = iota
+	pingFail
+	pingStart
+)
+
+// pingHandler handles the ping endpoints, which let cron jobs and scripts
+// update a box with a plain GET or POST, any body is used as the message.
+//
+//	/ping/{id}        the run succeeded, the box is green
+//	/ping/{id}/fail   the run failed, the box is red
+//	/ping/{id}/start  a run has started, the box keeps its status
+//
+// A success or fail after a start records how long the run took, a success
+// is amber if the run took longer than the box's expectedDuration.
+func pingHandler(kind pingKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxPingBodySize))
+		if err != nil {
+			handleApiErrorResponse(w, http.StatusBadRequest, err, "could not read body", true, true)
+			return
+		}
+
+		event, err := ping(id, kind, strings.TrimSpace(string(body)), time.Now())
+		if err != nil {
+			handleApiErrorResponse(w, http.StatusNotFound, err, "box not found", true, true)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(event); err != nil {
+			logger.Error(err.Error())
+		}
+	}
+}
+
+// ping records a ping on a box and updates it.
+func ping(id string, kind pingKind, body string, now time.Time) (api.Event, error) {
+	event := api.Event{ID: id}
+	message := body
+
+	var duration *api.Duration
+	var expected *api.Duration
+	err := boxStore.Update(id, func(box *api.Box) {
+		event.Status = box.Status
+		expected = box.ExpectedDuration
+
+		if kind == pingStart {
+			box.RunStarted = &now
+			return
+		}
+
+		if box.RunStarted != nil {
+			d := api.Duration(now.Sub(*box.RunStarted).Round(time.Millisecond))
+			duration = &d
+			box.LastDuration = duration
+			box.RunStarted = nil
+		}
+	})
+	if err != nil {
+		return event, err
+	}
+
+	switch kind {
+	case pingStart:
+		// Not a check-in, the box should still go stale if the run hangs,
+		// so uses the noUpdate type to leave LastUpdate alone.
+		event.Type = api.NoUpdate.String()
+		if message == "" {
+			message = "Run started."
+		}
+	case pingFail:
+		event.Status = api.Red
+		if message == "" {
+			message = "Run failed."
+			if duration != nil {
+				message = fmt.Sprintf("Run failed after %s.", duration)
+			}
+		}
+	default:
+		event.Status = api.Green
+		if message == "" {
+			message = "Run succeeded."
+			if duration != nil {
+				message = fmt.Sprintf("Run succeeded in %s.", duration)
+			}
+		}
+		if duration != nil && expected != nil && *duration > *expected {
+			event.Status = api.Amber
+			message = strings.TrimSpace(fmt.Sprintf("Run took %s, longer than the expected %s. %s", duration, expected, body))
+		}
+	}
+	event.Message = message
+
+	return event, update(event)
+}
+
+// runOverdue returns true if a box has a run in progress which has taken
+// longer than expected.
+func runOverdue(box api.Box) bool {
+	return box.RunStarted != nil && box.ExpectedDuration != nil &&
+		time.Since(*box.RunStarted) > box.ExpectedDuration.Duration()
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baelish/alive/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestPing(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+	}()
+
+	router := chi.NewRouter()
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		router.Method(method, "/ping/{id}", pingHandler(pingSuccess))
+		router.Method(method, "/ping/{id}/fail", pingHandler(pingFail))
+		router.Method(method, "/ping/{id}/start", pingHandler(pingStart))
+	}
+
+	tests := []struct {
+		name          string
+		box           api.Box
+		method        string
+		path          string
+		body          string
+		expectCode    int
+		expectStatus  api.Status
+		expectMessage string // prefix
+		expectRun     bool
+	}{
+		{
+			name:          "success",
+			box:           api.Box{ID: "job", Status: api.Grey},
+			method:        http.MethodGet,
+			path:          "/ping/job",
+			expectCode:    http.StatusOK,
+			expectStatus:  api.Green,
+			expectMessage: "Run succeeded.",
+		},
+		{
+			name:          "success with message",
+			box:           api.Box{ID: "job", Status: api.Red},
+			method:        http.MethodPost,
+			path:          "/ping/job",
+			body:          "backed up 42 files\n",
+			expectCode:    http.StatusOK,
+			expectStatus:  api.Green,
+			expectMessage: "backed up 42 files",
+		},
+		{
+			name:          "fail",
+			box:           api.Box{ID: "job", Status: api.Green},
+			method:        http.MethodPost,
+			path:          "/ping/job/fail",
+			body:          "disk full",
+			expectCode:    http.StatusOK,
+			expectStatus:  api.Red,
+			expectMessage: "disk full",
+		},
+		{
+			name:          "start keeps status",
+			box:           api.Box{ID: "job", Status: api.Red},
+			method:        http.MethodGet,
+			path:          "/ping/job/start",
+			expectCode:    http.StatusOK,
+			expectStatus:  api.Red,
+			expectMessage: "Run started.",
+			expectRun:     true,
+		},
+		{
+			name:          "run within expected duration",
+			box:           api.Box{ID: "job", Status: api.Green, RunStarted: ptr(time.Now().Add(-time.Minute)), ExpectedDuration: ptr(api.Duration(time.Hour))},
+			method:        http.MethodGet,
+			path:          "/ping/job",
+			expectCode:    http.StatusOK,
+			expectStatus:  api.Green,
+			expectMessage: "Run succeeded in 1m0", // plus the time taken by the test
+		},
+		{
+			name:          "run longer than expected",
+			box:           api.Box{ID: "job", Status: api.Green, RunStarted: ptr(time.Now().Add(-time.Hour)), ExpectedDuration: ptr(api.Duration(time.Minute))},
+			method:        http.MethodPost,
+			path:          "/ping/job",
+			body:          "done",
+			expectCode:    http.StatusOK,
+			expectStatus:  api.Amber,
+			expectMessage: "Run took 1h0m0",
+		},
+		{
+			name:       "unknown box",
+			box:        api.Box{ID: "job"},
+			method:     http.MethodGet,
+			path:       "/ping/other",
+			expectCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetBoxStore()
+			boxStore.Add(tt.box)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.expectCode {
+				t.Fatalf("expected code %d, got %d: %s", tt.expectCode, rec.Code, rec.Body)
+			}
+			if tt.expectCode != http.StatusOK {
+				return
+			}
+
+			box, err := boxStore.GetByID(tt.box.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if box.Status != tt.expectStatus {
+				t.Errorf("expected status %s, got %s", tt.expectStatus, box.Status)
+			}
+			if !strings.HasPrefix(box.LastMessage, tt.expectMessage) {
+				t.Errorf("expected message starting %q, got %q", tt.expectMessage, box.LastMessage)
+			}
+			if (box.RunStarted != nil) != tt.expectRun {
+				t.Errorf("expected run started %v, got %v", tt.expectRun, box.RunStarted)
+			}
+		})
+	}
+}
+
+func TestMaintainBoxes_OverdueRun(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+	}()
+
+	resetBoxStore()
+	now := time.Now()
+	boxStore.Add(api.Box{ID: "overdue", Status: api.Green, LastUpdate: now, RunStarted: ptr(now.Add(-time.Hour)), ExpectedDuration: ptr(api.Duration(time.Minute))})
+	boxStore.Add(api.Box{ID: "running", Status: api.Green, LastUpdate: now, RunStarted: ptr(now.Add(-time.Second)), ExpectedDuration: ptr(api.Duration(time.Minute))})
+	boxStore.Add(api.Box{ID: "already-red", Status: api.Red, LastUpdate: now, RunStarted: ptr(now.Add(-time.Hour)), ExpectedDuration: ptr(api.Duration(time.Minute))})
+
+	_, toUpdate := maintainBoxes()
+	if len(toUpdate) != 1 {
+		t.Fatalf("expected 1 update, got %d: %v", len(toUpdate), toUpdate)
+	}
+	if toUpdate[0].ID != "overdue" || toUpdate[0].Status != api.Amber {
+		t.Errorf("expected overdue to be amber, got %s %s", toUpdate[0].ID, toUpdate[0].Status)
+	}
+}
+
+func TestPing_StartIsNotACheckIn(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+	}()
+
+	resetBoxStore()
+	lastUpdate := time.Now().Add(-2 * time.Minute)
+	boxStore.Add(api.Box{ID: "job", Status: api.Green, LastUpdate: lastUpdate, MaxTBU: ptr(api.Duration(time.Minute))})
+
+	if _, err := ping("job", pingStart, "", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	box, err := boxStore.GetByID("job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !box.LastUpdate.Equal(lastUpdate) {
+		t.Errorf("expected a start to leave the last update alone, got %s", box.LastUpdate)
+	}
+
+	_, toUpdate := maintainBoxes()
+	if len(toUpdate) != 1 || toUpdate[0].ID != "job" || toUpdate[0].Status != api.NoUpdate {
+		t.Errorf("expected the job to go stale after its deadline, got %v", toUpdate)
+	}
+}
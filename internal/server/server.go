@@ -66,6 +66,11 @@ func Start() {
 	createStaticContent()
 	createDataFiles()
 	getBoxesFromDataFile()
+	getTemplatesFromDataFile()
+	getHooksFromDataFile()
+	getSyslogRulesFromDataFile()
+	getLayoutFromDataFile()
+	getDashboardsFromDataFile()
 
 	events = runSSE(ctx)
 	if events == nil || events.messages == nil {
@@ -77,6 +82,8 @@ func Start() {
 
 	go runKeepalives(ctx)
 	go maintenanceRoutine(ctx)
+	go runChecks(ctx)
+	runSyslog(ctx)
 
 	if options.ParentUrl != "" {
 		go parentUpdater(ctx)
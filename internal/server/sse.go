@@ -4,33 +4,150 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 )
 
+const (
+	// Number of messages which can be queued for a client before it is
+	// considered to be too slow.
+	sseClientBufferSize = 100
+
+	// Number of recent messages kept so they can be replayed to clients
+	// which reconnect with a Last-Event-ID.
+	sseReplayBufferSize = 1000
+
+	// Message sent to clients which have missed messages that can not be
+	// replayed, the client should reload its view of the boxes.
+	resyncMessage = `{"type": "resync"}`
+)
+
+// brokerMessage is a message with the ID the broker gave it. Transient
+// messages (keepalives) have an ID of 0 and are never replayed.
+type brokerMessage struct {
+	id   uint64
+	data string
+}
+
+// brokerClient is a single attached client (browser).
+type brokerClient struct {
+	// Channel over which the broker pushes messages to this client.
+	messages chan brokerMessage
+
+	// The last event ID the client saw before (re)connecting, only used
+	// when resume is set.
+	lastEventID uint64
+	resume      bool
+
+	// Set when the client fell behind and has been sent a resync, nothing
+	// more is queued for it until it has read everything queued so far.
+	desynced bool
+}
+
+func newBrokerClient() *brokerClient {
+	return &brokerClient{
+		messages: make(chan brokerMessage, sseClientBufferSize),
+	}
+}
+
+// replayBuffer is a fixed size ring buffer of the most recent messages.
+type replayBuffer struct {
+	messages []brokerMessage
+	start    int
+	size     int
+}
+
+func newReplayBuffer(size int) *replayBuffer {
+	return &replayBuffer{messages: make([]brokerMessage, size)}
+}
+
+func (rb *replayBuffer) push(m brokerMessage) {
+	if rb.size < len(rb.messages) {
+		rb.messages[(rb.start+rb.size)%len(rb.messages)] = m
+		rb.size++
+		return
+	}
+
+	rb.messages[rb.start] = m
+	rb.start = (rb.start + 1) % len(rb.messages)
+}
+
+// since returns all buffered messages with an ID greater than id. ok is false
+// if some of those messages are no longer buffered.
+func (rb *replayBuffer) since(id, lastID uint64) (messages []brokerMessage, ok bool) {
+	if id == lastID {
+		return nil, true
+	}
+
+	if id > lastID || rb.size == 0 || rb.messages[rb.start].id > id+1 {
+		return nil, false
+	}
+
+	for i := range rb.size {
+		m := rb.messages[(rb.start+i)%len(rb.messages)]
+		if m.id > id {
+			messages = append(messages, m)
+		}
+	}
+
+	return messages, true
+}
+
 // Broker which will be created in this program. It is responsible
 // for keeping a list of which clients (browsers) are currently attached
 // and broadcasting events (messages) to those clients.
 type Broker struct {
 
-	// Create a map of clients, the keys of the map are the channels
-	// over which we can push messages to attached clients.  (The values
-	// are just booleans and are meaningless.)
-	clients map[chan string]bool
+	// Create a map of clients, the keys of the map are the clients we can
+	// push messages to.  (The values are just booleans and are
+	// meaningless.)
+	clients map[*brokerClient]bool
 
 	// Channel into which new clients can be pushed
-	newClients chan chan string
+	newClients chan *brokerClient
 
 	// Channel into which disconnected clients should be pushed
-	defunctClients chan chan string
+	defunctClients chan *brokerClient
 
 	// Channel into which messages are pushed to be broadcast out
 	// to attached clients.
 	messages chan string
 
-	// Channel to query client count (for testing)
-	clientCount chan int
+	// Channel into which messages are pushed to be broadcast out without
+	// an ID, these are not kept for replay.
+	transient chan string
+
+	// Number of attached clients, kept by the Start goroutine so it can be
+	// read after the broker has stopped.
+	clientCount atomic.Int64
+
+	// ID of the last message broadcast and the recent messages available
+	// for replay, only accessed from the Start goroutine.
+	lastID  uint64
+	history *replayBuffer
+
+	// Number of times a slow client was told to resync and the number of
+	// messages which were dropped as a result.
+	resyncs atomic.Uint64
+	dropped atomic.Uint64
+}
+
+func newBroker() *Broker {
+	return &Broker{
+		clients:        make(map[*brokerClient]bool),
+		newClients:     make(chan *brokerClient),
+		defunctClients: make(chan *brokerClient),
+		messages:       make(chan string),
+		transient:      make(chan string),
+		// Start IDs from the current time so they keep increasing across
+		// restarts, a client reconnecting after a restart will then be
+		// told to resync rather than being replayed the wrong messages.
+		lastID:  uint64(time.Now().UnixNano()),
+		history: newReplayBuffer(sseReplayBufferSize),
+	}
 }
 
 // Start method, this Broker method starts a new goroutine.  It handles
@@ -45,59 +162,153 @@ func (b *Broker) Start(ctx context.Context) {
 		for {
 
 			// Block until we receive from one of the
-			// three following channels.
+			// following channels.
 			select {
 
 			case <-ctx.Done():
 				return
 
-			case s := <-b.newClients:
+			case c := <-b.newClients:
 
 				// There is a new client attached and we
 				// want to start sending them messages.
-				b.clients[s] = true
+				b.clients[c] = true
+				b.clientCount.Store(int64(len(b.clients)))
 				logger.Info("Added new client", zap.Int("currentClientCount", len(b.clients)))
 
-			case s := <-b.defunctClients:
+				if c.resume {
+					b.replay(c)
+				}
+
+			case c := <-b.defunctClients:
 
 				// A client has detached and we want to
 				// stop sending them messages.
-				delete(b.clients, s)
-				close(s)
+				delete(b.clients, c)
+				close(c.messages)
+				b.clientCount.Store(int64(len(b.clients)))
 
 				logger.Info("Removed client", zap.Int("currentClientCount", len(b.clients)))
 
-			case b.clientCount <- len(b.clients):
-				// Respond to client count query (non-blocking send from caller's perspective)
-
 			case msg := <-b.messages:
 
-				// There is a new message to send.  For each
-				// attached client, push the new message
-				// into the client's message channel.
-				for s := range b.clients {
-					// Non-blocking send to prevent slow clients from blocking broker
-					select {
-					case s <- msg:
-						// Message sent successfully
-					default:
-						// Client's buffer is full, drop the message
-						// This prevents one slow client from blocking all others
-						logger.Warn("Dropped message for slow client")
-					}
-				}
+				// There is a new message to send.  Give it the
+				// next ID, keep it for replay then push it into
+				// each attached client's message channel.
+				b.lastID++
+				m := brokerMessage{id: b.lastID, data: msg}
+				b.history.push(m)
+				b.broadcast(m)
+
+			case msg := <-b.transient:
+				b.broadcast(brokerMessage{data: msg})
 			}
 		}
 	}()
 }
 
+func (b *Broker) broadcast(m brokerMessage) {
+	for c := range b.clients {
+		b.send(c, m)
+	}
+}
+
+func (b *Broker) send(c *brokerClient, m brokerMessage) {
+	if c.desynced {
+		if len(c.messages) > 0 {
+			// Still catching up, the resync will pick this up.
+			b.dropped.Add(1)
+			return
+		}
+		c.desynced = false
+	}
+
+	// Non-blocking send to prevent slow clients from blocking broker
+	select {
+	case c.messages <- m:
+		// Message sent successfully
+	default:
+		// Client's buffer is full, this prevents one slow client from
+		// blocking all others.
+		b.desync(c)
+	}
+}
+
+// desync throws away everything queued for a client that can not keep up and
+// tells it to resync instead.
+func (b *Broker) desync(c *brokerClient) {
+	dropped := 1
+	for drained := false; !drained; {
+		select {
+		case <-c.messages:
+			dropped++
+		default:
+			drained = true
+		}
+	}
+
+	c.messages <- brokerMessage{id: b.lastID, data: resyncMessage}
+	c.desynced = true
+
+	b.dropped.Add(uint64(dropped))
+	b.resyncs.Add(1)
+	logger.Warn("Client too slow, requesting resync", zap.Int("droppedMessages", dropped))
+}
+
+// replay sends a reconnecting client the messages it missed, or tells it to
+// resync if they are no longer available.
+func (b *Broker) replay(c *brokerClient) {
+	missed, ok := b.history.since(c.lastEventID, b.lastID)
+	if !ok || len(missed) > cap(c.messages) {
+		logger.Info("Unable to replay missed messages, requesting resync", zap.Uint64("lastEventID", c.lastEventID))
+		b.send(c, brokerMessage{id: b.lastID, data: resyncMessage})
+		return
+	}
+
+	logger.Debug("Replaying missed messages", zap.Uint64("lastEventID", c.lastEventID), zap.Int("count", len(missed)))
+	for _, m := range missed {
+		b.send(c, m)
+	}
+}
+
 // ClientCount returns the current number of connected clients (thread-safe)
 func (b *Broker) ClientCount() int {
-	return <-b.clientCount
+	return int(b.clientCount.Load())
 }
 
-// This Broker method handles and HTTP request at the "/events/" URL.
+// ResyncCount returns the number of times a slow client has been told to
+// resync (thread-safe)
+func (b *Broker) ResyncCount() uint64 {
+	return b.resyncs.Load()
+}
+
+// DroppedCount returns the number of messages dropped for slow clients
+// (thread-safe)
+func (b *Broker) DroppedCount() uint64 {
+	return b.dropped.Load()
+}
+
+// This Broker method handles and HTTP request at the "/events/" URL. Adding
+// ?id= limits the box events sent to the boxes given, like subscribing does
+// on the WebSocket.
 func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ids := r.URL.Query()["id"]
+	if len(ids) == 0 {
+		b.serveEvents(w, r, nil)
+		return
+	}
+
+	var filter eventFilter
+	filter.subscribe(ids)
+	b.serveEvents(w, r, func(data string) (string, bool) {
+		return data, filter.matches(data)
+	})
+}
+
+// serveEvents streams messages to the client as server-sent events. If
+// translate is set each message is passed through it first, and dropped if
+// it returns false.
+func (b *Broker) serveEvents(w http.ResponseWriter, r *http.Request, translate func(string) (string, bool)) {
 
 	// Make sure that the writer supports flushing.
 	f, ok := w.(http.Flusher)
@@ -106,14 +317,23 @@ func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create a new channel, over which the broker can
-	// send this client messages.
-	// Buffered to prevent slow clients from blocking the broker
-	messageChan := make(chan string, 100)
+	// Create a new client, over which the broker can send this client
+	// messages. If the browser is reconnecting it tells us the last
+	// message it saw so we can replay anything it missed.
+	c := newBrokerClient()
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			logger.Warn("Ignoring invalid Last-Event-ID", zap.String("lastEventID", lastEventID))
+		} else {
+			c.lastEventID = id
+			c.resume = true
+		}
+	}
 
 	// Add this client to the map of those that should
 	// receive updates
-	b.newClients <- messageChan
+	b.newClients <- c
 
 	// Listen to the closing of the http connection via the request context
 	// The context is cancelled when the client disconnects
@@ -121,7 +341,7 @@ func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		<-r.Context().Done()
 		// Remove this client from the map of attached clients
 		// when the client disconnects
-		b.defunctClients <- messageChan
+		b.defunctClients <- c
 		logger.Warn("http connection just closed")
 	}()
 
@@ -134,17 +354,27 @@ func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Don't close the connection, instead loop endlessly.
 	for {
 
-		// Read from our messageChan.
-		msg, open := <-messageChan
+		// Read from our message channel.
+		msg, open := <-c.messages
 
 		if !open {
-			// If our messageChan was closed, this means that the client has
-			// disconnected.
+			// If our message channel was closed, this means that the
+			// client has disconnected.
 			break
 		}
 
+		if translate != nil {
+			var ok bool
+			if msg.data, ok = translate(msg.data); !ok {
+				continue
+			}
+		}
+
 		// Write to the ResponseWriter, `w`.
-		fmt.Fprintf(w, "data: %s\n\n", msg)
+		if msg.id != 0 {
+			fmt.Fprintf(w, "id: %d\n", msg.id)
+		}
+		fmt.Fprintf(w, "data: %s\n\n", msg.data)
 
 		// Flush the response.  This is only possible if
 		// the repsonse supports streaming.
@@ -161,7 +391,7 @@ func runKeepalives(ctx context.Context) {
 		logger.Info("Starting keepalive routine")
 	}
 	// Generate a regular keepalive message that gets pushed
-	// into the Broker's messages channel and are then broadcast
+	// into the Broker's transient channel and are then broadcast
 	// out to any clients that are attached.
 	for {
 		select {
@@ -175,7 +405,7 @@ func runKeepalives(ctx context.Context) {
 		}
 
 		// Send a keepalive
-		events.messages <- `{"type": "keepalive"}`
+		events.transient <- `{"type": "keepalive"}`
 	}
 }
 
@@ -186,13 +416,7 @@ func runSSE(ctx context.Context) (b *Broker) {
 	}
 
 	// Make a new Broker instance
-	b = &Broker{
-		clients:        make(map[chan string]bool),
-		newClients:     make(chan (chan string)),
-		defunctClients: make(chan (chan string)),
-		messages:       make(chan string),
-		clientCount:    make(chan int),
-	}
+	b = newBroker()
 
 	// Start processing events
 	b.Start(ctx)
@@ -203,5 +427,9 @@ func runSSE(ctx context.Context) (b *Broker) {
 	// request to "/events/".
 	http.Handle("/events/", b)
 
+	// The same messages are available over a WebSocket for browsers which
+	// sit behind proxies that buffer event streams.
+	http.HandleFunc("/ws", b.ServeWebSocket)
+
 	return b
 }
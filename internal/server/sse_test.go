@@ -2,8 +2,10 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -15,13 +17,7 @@ func TestBroker_Start(t *testing.T) {
 	initTestLogger()
 
 	t.Run("adds new clients", func(t *testing.T) {
-		broker := &Broker{
-			clients:        make(map[chan string]bool),
-			newClients:     make(chan chan string),
-			defunctClients: make(chan chan string),
-			messages:       make(chan string),
-			clientCount:    make(chan int),
-		}
+		broker := newBroker()
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -29,7 +25,7 @@ func TestBroker_Start(t *testing.T) {
 		broker.Start(ctx)
 
 		// Add a new client
-		clientChan := make(chan string, 1)
+		clientChan := newBrokerClient()
 		broker.newClients <- clientChan
 
 		// Give it a moment to process
@@ -41,14 +37,30 @@ func TestBroker_Start(t *testing.T) {
 		}
 	})
 
-	t.Run("removes defunct clients", func(t *testing.T) {
-		broker := &Broker{
-			clients:        make(map[chan string]bool),
-			newClients:     make(chan chan string),
-			defunctClients: make(chan chan string),
-			messages:       make(chan string),
-			clientCount:    make(chan int),
+	t.Run("counts clients after stopping", func(t *testing.T) {
+		broker := newBroker()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		broker.Start(ctx)
+		broker.newClients <- newBrokerClient()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		time.Sleep(10 * time.Millisecond)
+
+		done := make(chan int)
+		go func() { done <- broker.ClientCount() }()
+		select {
+		case count := <-done:
+			if count != 1 {
+				t.Errorf("expected 1 client, got %d", count)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("ClientCount blocked after the broker stopped")
 		}
+	})
+
+	t.Run("removes defunct clients", func(t *testing.T) {
+		broker := newBroker()
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -56,7 +68,7 @@ func TestBroker_Start(t *testing.T) {
 		broker.Start(ctx)
 
 		// Add a client
-		clientChan := make(chan string, 1)
+		clientChan := newBrokerClient()
 		broker.newClients <- clientChan
 		time.Sleep(10 * time.Millisecond)
 
@@ -74,20 +86,14 @@ func TestBroker_Start(t *testing.T) {
 		}
 
 		// Verify the channel was closed
-		_, open := <-clientChan
+		_, open := <-clientChan.messages
 		if open {
 			t.Error("expected client channel to be closed, but it was still open")
 		}
 	})
 
 	t.Run("broadcasts messages to all clients", func(t *testing.T) {
-		broker := &Broker{
-			clients:        make(map[chan string]bool),
-			newClients:     make(chan chan string),
-			defunctClients: make(chan chan string),
-			messages:       make(chan string),
-			clientCount:    make(chan int),
-		}
+		broker := newBroker()
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -95,9 +101,9 @@ func TestBroker_Start(t *testing.T) {
 		broker.Start(ctx)
 
 		// Add multiple clients
-		client1 := make(chan string, 1)
-		client2 := make(chan string, 1)
-		client3 := make(chan string, 1)
+		client1 := newBrokerClient()
+		client2 := newBrokerClient()
+		client3 := newBrokerClient()
 
 		broker.newClients <- client1
 		broker.newClients <- client2
@@ -110,31 +116,25 @@ func TestBroker_Start(t *testing.T) {
 		time.Sleep(10 * time.Millisecond)
 
 		// Verify all clients received the message
-		if msg := <-client1; msg != testMessage {
-			t.Errorf("client1: expected %q, got %q", testMessage, msg)
+		if msg := <-client1.messages; msg.data != testMessage {
+			t.Errorf("client1: expected %q, got %q", testMessage, msg.data)
 		}
-		if msg := <-client2; msg != testMessage {
-			t.Errorf("client2: expected %q, got %q", testMessage, msg)
+		if msg := <-client2.messages; msg.data != testMessage {
+			t.Errorf("client2: expected %q, got %q", testMessage, msg.data)
 		}
-		if msg := <-client3; msg != testMessage {
-			t.Errorf("client3: expected %q, got %q", testMessage, msg)
+		if msg := <-client3.messages; msg.data != testMessage {
+			t.Errorf("client3: expected %q, got %q", testMessage, msg.data)
 		}
 	})
 
 	t.Run("stops when context is cancelled", func(t *testing.T) {
-		broker := &Broker{
-			clients:        make(map[chan string]bool),
-			newClients:     make(chan chan string),
-			defunctClients: make(chan chan string),
-			messages:       make(chan string),
-			clientCount:    make(chan int),
-		}
+		broker := newBroker()
 
 		ctx, cancel := context.WithCancel(context.Background())
 		broker.Start(ctx)
 
 		// Add a client to verify broker is running
-		clientChan := make(chan string, 1)
+		clientChan := newBrokerClient()
 		broker.newClients <- clientChan
 		time.Sleep(10 * time.Millisecond)
 
@@ -150,7 +150,7 @@ func TestBroker_Start(t *testing.T) {
 		// We can't directly test if the goroutine stopped, but we can verify
 		// that the broker becomes unresponsive
 		select {
-		case broker.newClients <- make(chan string):
+		case broker.newClients <- newBrokerClient():
 			// This might succeed immediately if buffered, so we need a timeout
 			time.Sleep(50 * time.Millisecond)
 		case <-time.After(100 * time.Millisecond):
@@ -232,13 +232,7 @@ func TestBroker_ServeHTTP(t *testing.T) {
 	initTestLogger()
 
 	t.Run("sets correct SSE headers", func(t *testing.T) {
-		broker := &Broker{
-			clients:        make(map[chan string]bool),
-			newClients:     make(chan chan string),
-			defunctClients: make(chan chan string),
-			messages:       make(chan string),
-			clientCount:    make(chan int),
-		}
+		broker := newBroker()
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -287,13 +281,7 @@ func TestBroker_ServeHTTP(t *testing.T) {
 	})
 
 	t.Run("sends messages in SSE format", func(t *testing.T) {
-		broker := &Broker{
-			clients:        make(map[chan string]bool),
-			newClients:     make(chan chan string),
-			defunctClients: make(chan chan string),
-			messages:       make(chan string),
-			clientCount:    make(chan int),
-		}
+		broker := newBroker()
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -331,14 +319,42 @@ func TestBroker_ServeHTTP(t *testing.T) {
 		}
 	})
 
-	t.Run("registers and unregisters client", func(t *testing.T) {
-		broker := &Broker{
-			clients:        make(map[chan string]bool),
-			newClients:     make(chan chan string),
-			defunctClients: make(chan chan string),
-			messages:       make(chan string),
-			clientCount:    make(chan int),
+	t.Run("only sends events for the boxes asked for", func(t *testing.T) {
+		broker := newBroker()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		broker.Start(ctx)
+
+		testWriter := &testResponseWriter{
+			header: make(http.Header),
+			body:   make([]byte, 0),
 		}
+		req := httptest.NewRequest("GET", "/events/?id=box1", nil)
+
+		go broker.ServeHTTP(testWriter, req)
+
+		// Wait for client to be registered
+		time.Sleep(20 * time.Millisecond)
+
+		broker.messages <- `{"type": "updateBox", "id": "box2"}`
+		broker.messages <- `{"type": "updateBox", "id": "box1"}`
+		broker.messages <- `{"type": "resync"}`
+
+		// Give it time to write
+		time.Sleep(20 * time.Millisecond)
+
+		body := string(testWriter.GetBody())
+		if strings.Contains(body, "box2") {
+			t.Errorf("expected no events for box2, got %q", body)
+		}
+		if !strings.Contains(body, `"id": "box1"`) || !strings.Contains(body, "resync") {
+			t.Errorf("expected events for box1 and the resync, got %q", body)
+		}
+	})
+
+	t.Run("registers and unregisters client", func(t *testing.T) {
+		broker := newBroker()
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -399,7 +415,7 @@ func TestRunSSE(t *testing.T) {
 	}
 
 	// Verify the broker is actually running by adding a client
-	clientChan := make(chan string, 1)
+	clientChan := newBrokerClient()
 	broker.newClients <- clientChan
 	time.Sleep(10 * time.Millisecond)
 
@@ -424,13 +440,9 @@ func TestRunKeepalives(t *testing.T) {
 		options.Debug = false
 
 		// Create a test broker
-		events = &Broker{
-			clients:        make(map[chan string]bool),
-			newClients:     make(chan chan string),
-			defunctClients: make(chan chan string),
-			messages:       make(chan string, 10), // Buffered to catch messages
-			clientCount:    make(chan int),
-		}
+		events = newBroker()
+		events.messages = make(chan string, 10) // Buffered to catch messages
+		events.transient = make(chan string, 10)
 
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 		defer cancel()
@@ -454,13 +466,9 @@ func TestRunKeepalives(t *testing.T) {
 	t.Run("stops when context is cancelled", func(t *testing.T) {
 		options.Debug = false
 
-		events = &Broker{
-			clients:        make(map[chan string]bool),
-			newClients:     make(chan chan string),
-			defunctClients: make(chan chan string),
-			messages:       make(chan string, 10),
-			clientCount:    make(chan int),
-		}
+		events = newBroker()
+		events.messages = make(chan string, 10)
+		events.transient = make(chan string, 10)
 
 		ctx, cancel := context.WithCancel(context.Background())
 
@@ -486,13 +494,9 @@ func TestRunKeepalives(t *testing.T) {
 	t.Run("respects debug flag", func(t *testing.T) {
 		options.Debug = true
 
-		events = &Broker{
-			clients:        make(map[chan string]bool),
-			newClients:     make(chan chan string),
-			defunctClients: make(chan chan string),
-			messages:       make(chan string, 10),
-			clientCount:    make(chan int),
-		}
+		events = newBroker()
+		events.messages = make(chan string, 10)
+		events.transient = make(chan string, 10)
 
 		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 		defer cancel()
@@ -512,3 +516,161 @@ func TestRunKeepalives(t *testing.T) {
 		options.Debug = false
 	})
 }
+
+func TestReplayBuffer(t *testing.T) {
+	rb := newReplayBuffer(3)
+	for id := uint64(1); id <= 5; id++ {
+		rb.push(brokerMessage{id: id, data: strconv.FormatUint(id, 10)})
+	}
+
+	tests := []struct {
+		name      string
+		id        uint64
+		lastID    uint64
+		expectIDs []uint64
+		expectOK  bool
+	}{
+		{name: "up to date", id: 5, lastID: 5, expectOK: true},
+		{name: "missed one", id: 4, lastID: 5, expectIDs: []uint64{5}, expectOK: true},
+		{name: "missed all buffered", id: 2, lastID: 5, expectIDs: []uint64{3, 4, 5}, expectOK: true},
+		{name: "gap too large", id: 1, lastID: 5, expectOK: false},
+		{name: "id from the future", id: 9, lastID: 5, expectOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missed, ok := rb.since(tt.id, tt.lastID)
+			if ok != tt.expectOK {
+				t.Fatalf("expected ok=%v, got %v", tt.expectOK, ok)
+			}
+			if len(missed) != len(tt.expectIDs) {
+				t.Fatalf("expected %d messages, got %d", len(tt.expectIDs), len(missed))
+			}
+			for i, m := range missed {
+				if m.id != tt.expectIDs[i] {
+					t.Errorf("message %d: expected id %d, got %d", i, tt.expectIDs[i], m.id)
+				}
+			}
+		})
+	}
+
+	t.Run("empty buffer", func(t *testing.T) {
+		if _, ok := newReplayBuffer(3).since(1, 2); ok {
+			t.Error("expected replay from an empty buffer to fail")
+		}
+	})
+}
+
+func TestBroker_Replay(t *testing.T) {
+	initTestLogger()
+
+	broker := newBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker.Start(ctx)
+
+	// Broadcast some messages before anyone is attached
+	firstID := broker.lastID + 1
+	for _, msg := range []string{"one", "two", "three"} {
+		broker.messages <- msg
+	}
+
+	t.Run("replays missed messages", func(t *testing.T) {
+		c := newBrokerClient()
+		c.lastEventID = firstID
+		c.resume = true
+		broker.newClients <- c
+
+		for _, expected := range []string{"two", "three"} {
+			select {
+			case msg := <-c.messages:
+				if msg.data != expected {
+					t.Errorf("expected %q, got %q", expected, msg.data)
+				}
+			case <-time.After(100 * time.Millisecond):
+				t.Fatalf("timed out waiting for %q", expected)
+			}
+		}
+	})
+
+	t.Run("requests resync when messages are no longer available", func(t *testing.T) {
+		c := newBrokerClient()
+		c.lastEventID = firstID - 10
+		c.resume = true
+		broker.newClients <- c
+
+		select {
+		case msg := <-c.messages:
+			if msg.data != resyncMessage {
+				t.Errorf("expected resync message, got %q", msg.data)
+			}
+			if msg.id != firstID+2 {
+				t.Errorf("expected resync to carry the latest id %d, got %d", firstID+2, msg.id)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("timed out waiting for resync")
+		}
+	})
+
+	t.Run("serves Last-Event-ID header", func(t *testing.T) {
+		testWriter := &testResponseWriter{
+			header:    make(http.Header),
+			wroteOnce: make(chan struct{}),
+		}
+		req := httptest.NewRequest("GET", "/events/", nil)
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(firstID+1, 10))
+
+		go broker.ServeHTTP(testWriter, req)
+		testWriter.WaitForWrite()
+		time.Sleep(10 * time.Millisecond)
+
+		expected := fmt.Sprintf("id: %d\ndata: three\n\n", firstID+2)
+		if body := string(testWriter.GetBody()); body != expected {
+			t.Errorf("expected body %q, got %q", expected, body)
+		}
+	})
+}
+
+func TestBroker_SlowClient(t *testing.T) {
+	initTestLogger()
+
+	broker := newBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker.Start(ctx)
+
+	slow := newBrokerClient()
+	broker.newClients <- slow
+
+	// Overflow the slow client's buffer by two messages.
+	for i := range sseClientBufferSize + 2 {
+		broker.messages <- strconv.Itoa(i)
+	}
+
+	if count := broker.ResyncCount(); count != 1 {
+		t.Errorf("expected 1 resync, got %d", count)
+	}
+	if count := broker.DroppedCount(); count != sseClientBufferSize+2 {
+		t.Errorf("expected %d dropped messages, got %d", sseClientBufferSize+2, count)
+	}
+
+	// The queue should have been replaced by a single resync.
+	msg := <-slow.messages
+	if msg.data != resyncMessage {
+		t.Errorf("expected resync message, got %q", msg.data)
+	}
+	if len(slow.messages) != 0 {
+		t.Errorf("expected nothing else queued, got %d messages", len(slow.messages))
+	}
+
+	// Once caught up, the client receives messages again.
+	broker.messages <- "caught up"
+	select {
+	case msg := <-slow.messages:
+		if msg.data != "caught up" {
+			t.Errorf("expected %q, got %q", "caught up", msg.data)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("timed out waiting for message after resync")
+	}
+}
@@ -8,10 +8,97 @@ if (
   location.reload();
 }
 
-// Register with box event source
-let source = new EventSource("/events/");
-source.onmessage = function (event) {
-  event = JSON.parse(event.data);
+// Register for box events. Server-sent events are used by default, if they
+// stop arriving (some proxies buffer event streams) we switch to a WebSocket,
+// and fall back to server-sent events if the WebSocket can't connect.
+let transport;
+let transportTimer;
+let reconnected = false;
+
+function connectEventSource() {
+  // Only hear about this box when looking at its info page.
+  let id = boxPageID();
+  let source = new EventSource(
+    id === null ? "/events/" : `/events/?id=${encodeURIComponent(id)}`,
+  );
+  let switching = reconnected;
+  transport = source;
+  let stalled = function () {
+    source.close();
+    reconnected = true;
+    connectWebSocket();
+  };
+  transportTimer = setTimeout(stalled, 10 * 1000);
+  source.onopen = function () {
+    // The browser replays anything missed when it reconnects by itself,
+    // but not when we have switched over from a WebSocket.
+    if (switching) {
+      switching = false;
+      resync();
+    }
+  };
+  source.onmessage = function (event) {
+    clearTimeout(transportTimer);
+    transportTimer = setTimeout(stalled, 10 * 1000);
+    handleEvent(event.data);
+  };
+}
+
+function webSocketURL() {
+  let protocol = window.location.protocol === "https:" ? "wss:" : "ws:";
+  return `${protocol}//${window.location.host}/ws`;
+}
+
+function connectWebSocket() {
+  let opened = false;
+  let socket = new WebSocket(webSocketURL());
+  transport = socket;
+  socket.onopen = function () {
+    opened = true;
+    // Only hear about this box when looking at its info page.
+    let id = boxPageID();
+    if (id !== null) {
+      socket.send(JSON.stringify({ type: "subscribe", ids: [id] }));
+    }
+    // Anything sent while we were disconnected has been missed.
+    if (reconnected) {
+      resync();
+    }
+  };
+  socket.onmessage = function (event) {
+    handleEvent(event.data);
+  };
+  socket.onclose = function () {
+    reconnected = true;
+    if (opened) {
+      setTimeout(connectWebSocket, 3 * 1000);
+    } else {
+      connectEventSource();
+    }
+  };
+}
+
+connectEventSource();
+
+// The slug of the dashboard being shown at /d/{slug}, if any.
+function dashboardSlug() {
+  let match = window.location.pathname.match(/^\/d\/([^/]+)$/);
+  return match ? match[1] : null;
+}
+
+// The ID of the box being shown at /box/{id}, if any.
+function boxPageID() {
+  let match = window.location.pathname.match(/^\/box\/(.+)$/);
+  return match ? decodeURIComponent(match[1]) : null;
+}
+
+// Whether the page shows a grid of boxes, "/" may instead list the dashboards.
+function isDashboard() {
+  return document.querySelector("#big-box[data-box-grid]") !== null;
+}
+
+function handleEvent(data) {
+  let event = JSON.parse(data);
   switch (event.type) {
     case "keepalive":
       keepalive();
@@ -20,35 +107,107 @@ source.onmessage = function (event) {
 
     case "updateBox":
       if (
-        window.location.pathname === "/" ||
+        isDashboard() ||
         window.location.pathname === `/box/${event.id}`
       ) {
         updateBox(event);
+        updateSummary();
+        flashRed(event);
+        raiseAlert(event);
+        if (boxPageID() !== null && event.previousStatus !== event.status) {
+          // Fetch the timelines again.
+          resync();
+        }
       }
 
       break;
 
     case "deleteBox":
       if (
-        window.location.pathname === "/" ||
+        isDashboard() ||
         window.location.pathname === `/box/${event.id}`
       ) {
         deleteBox(event.id);
+        updateSummary();
+        document.body.onresize();
       }
 
       break;
 
     case "createBox":
-      if (window.location.pathname === "/") {
+      if (isDashboard() && dashboardSlug() === null) {
         createBox(event.after, event.box);
+        updateSummary();
+        document.body.onresize();
+      } else if (dashboardSlug() !== null) {
+        // Only the server knows if the box belongs on this dashboard and
+        // where it goes.
+        resync();
       }
 
       break;
 
+    case "resync":
+      // Events were missed, either while reconnecting or because we fell
+      // behind, so fetch everything again.
+      resync();
+
+      break;
+
+    case "ackBox":
+      ackBox(event.id);
+
+      break;
+
     case "reloadPage":
       location.reload();
   }
-};
+}
+
+// Acknowledge a box, this needs a WebSocket so one is opened if we are
+// currently using server-sent events.
+function acknowledge(id) {
+  let message = JSON.stringify({ type: "ack", id: id });
+  if (transport instanceof WebSocket && transport.readyState === WebSocket.OPEN) {
+    transport.send(message);
+    return;
+  }
+
+  let socket = new WebSocket(webSocketURL());
+  socket.onopen = function () {
+    socket.send(message);
+    socket.close();
+  };
+}
+
+// Mark a box as acknowledged
+function ackBox(id) {
+  let target = document.getElementById(id);
+  if (target !== null) {
+    target.classList.add("acknowledged");
+  }
+}
+
+// Refetch the current page and swap in the up to date boxes.
+function resync() {
+  fetch(window.location.href, { cache: "no-store" })
+    .then((response) => {
+      if (!response.ok) {
+        throw new Error(response.statusText);
+      }
+      return response.text();
+    })
+    .then((html) => {
+      let doc = new DOMParser().parseFromString(html, "text/html");
+      document.getElementById("big-box").innerHTML =
+        doc.getElementById("big-box").innerHTML;
+      applyEditMode();
+      loadAlertSettings();
+      filterMessages();
+      document.body.onresize();
+    })
+    .catch(() => location.reload());
+}
 
 // Box tooltip
 function boxHover(tip) {
@@ -65,7 +224,134 @@ function boxOut() {
 
 // Box click
 function boxClick(id) {
-  window.location.href = "/box/" + id;
+  if (editing) {
+    return;
+  }
+  // Keep the theme when moving between pages.
+  let theme = new URLSearchParams(window.location.search).get("theme");
+  window.location.href =
+    "/box/" + id + (theme ? "?theme=" + encodeURIComponent(theme) : "");
+}
+
+// Layout edit mode, boxes can be dragged to a new position or section and the
+// layout is saved after each move.
+let editing = false;
+
+function toggleEditMode() {
+  editing = !editing;
+  applyEditMode();
+}
+
+function applyEditMode() {
+  document.body.classList.toggle("editing", editing);
+  let button = document.querySelector(".edit-layout");
+  if (button !== null) {
+    button.textContent = editing ? "Done" : "Edit layout";
+  }
+  let tiles = document.getElementsByClassName("tile");
+  for (let i = 0; i < tiles.length; i++) {
+    tiles[i].draggable = editing;
+  }
+}
+
+// The element a box dropped on target should be placed in or before.
+function dropTarget(target) {
+  if (!editing || !(target instanceof Element)) {
+    return null;
+  }
+  return target.closest(".tile, .section, #status-bar");
+}
+
+document.addEventListener("dragstart", function (event) {
+  if (editing && event.target.classList.contains("tile")) {
+    event.dataTransfer.setData("text/plain", event.target.id);
+  }
+});
+
+document.addEventListener("dragover", function (event) {
+  if (dropTarget(event.target) !== null) {
+    event.preventDefault();
+  }
+});
+
+document.addEventListener("drop", function (event) {
+  let target = dropTarget(event.target);
+  let box = document.getElementById(event.dataTransfer.getData("text/plain"));
+  if (target === null || box === null || target === box) {
+    return;
+  }
+  event.preventDefault();
+
+  if (target.classList.contains("tile")) {
+    target.insertAdjacentElement("beforebegin", box);
+  } else if (target.classList.contains("section")) {
+    target.appendChild(box);
+  } else {
+    // Dropped on the status bar, make it the first ungrouped box.
+    target.insertAdjacentElement("afterend", box);
+  }
+  saveLayout();
+});
+
+// Add an empty section to drop boxes in.
+function addSection() {
+  let title = prompt("Section heading");
+  if (!title) {
+    return;
+  }
+  let id = title
+    .toLowerCase()
+    .replace(/[^a-z0-9_.-]+/g, "-")
+    .replace(/^-+|-+$/g, "");
+  if (id === "" || document.getElementById(`group-${id}`) !== null) {
+    return;
+  }
+
+  let section = document.createElement("div");
+  section.className = "section";
+  section.dataset.group = id;
+  let heading = document.createElement("h2");
+  heading.className = "heading";
+  heading.id = `group-${id}`;
+  heading.textContent = title;
+  section.appendChild(heading);
+  document.getElementById("big-box").appendChild(section);
+  saveLayout();
+}
+
+// Send the layout shown to the server, numbering the boxes in each section.
+function saveLayout() {
+  let layout = { groups: [], boxes: [] };
+  let sections = document.getElementsByClassName("section");
+  for (let i = 0; i < sections.length; i++) {
+    layout.groups.push({
+      id: sections[i].dataset.group,
+      title: sections[i].getElementsByClassName("heading")[0].textContent,
+    });
+  }
+
+  let orders = {};
+  let tiles = document.getElementsByClassName("tile");
+  for (let i = 0; i < tiles.length; i++) {
+    let section = tiles[i].closest(".section");
+    let group = section === null ? "" : section.dataset.group;
+    orders[group] = (orders[group] || 0) + 1;
+    layout.boxes.push({ id: tiles[i].id, group: group, order: orders[group] });
+  }
+
+  let slug = dashboardSlug();
+  let url =
+    slug === null ? "/api/v1/layout" : `/api/v1/dashboards/${slug}/layout`;
+  fetch(url, {
+    method: "PUT",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(layout),
+  }).then((response) => {
+    if (!response.ok) {
+      alert(`Failed to save layout: ${response.statusText}`);
+      resync();
+    }
+  });
 }
 
 // Create box
@@ -78,31 +364,53 @@ function createBox(after, box) {
   }
 
   let divContent = `
-    <div onclick='boxClick(this.id)' onmouseover='boxHover("${box.name}")' onmouseout='boxOut()' id='${box.id}' class='${box.status} ${box.size} box'>
+    <div onclick='boxClick(this.id)' onmouseover='boxHover("${box.name}")' onmouseout='boxOut()' id='${box.id}' class='${box.status} ${box.size} tile box' draggable='${editing}'>
         <p class='title'>${title}</p>
         <p class='message'>${box.lastMessage}</p>
         <p class='lastUpdated'>${box.lastUpdate}</p>
         <p class='maxTBU'>${box.maxTBU}</p>
         <p class='expireAfter'>${box.expireAfter}</p>
+        ${runbookLink(box.runbook)}
     </div>
   `;
 
   let precedingBox = document.getElementById(after);
+  if (precedingBox === null) {
+    // A section we don't have yet.
+    resync();
+    return;
+  }
   precedingBox.insertAdjacentHTML("afterEnd", divContent);
 }
 
+// Link to a box's runbook, shown on the tile when the box is red.
+function runbookLink(url) {
+  if (!url) {
+    return "";
+  }
+  let href = encodeURI(url).replace(/'/g, "%27");
+  return `<a class='runbook' href='${href}' target='_blank' rel='noopener noreferrer' onclick='event.stopPropagation()'>Runbook</a>`;
+}
+
 // Remove box
 function deleteBox(id) {
   let target = document.getElementById(id);
-  target.parentNode.removeChild(target);
+  if (target !== null) {
+    target.parentNode.removeChild(target);
+  }
 }
 
 // Update box
 function updateBox(event) {
   let targetBox = document.getElementById(event.id);
 
-  if (targetBox !== null) {
-    changeAlertLevel(targetBox, event.status, event.lastMessage);
+  if (targetBox === null) {
+    // Not on this dashboard.
+    return;
+  }
+  changeAlertLevel(targetBox, event.status, event.lastMessage);
+  if (event.metrics) {
+    updateMetrics(targetBox, event.metrics);
   }
 
   if (event.maxTBU) {
@@ -136,20 +444,24 @@ function updateBox(event) {
 
 // keepalive
 let lastKa;
+let keepaliveLost = false;
 function keepalive() {
   let ct = new Date().getTime();
   if (lastKa && lastKa + 60000 < ct) {
     location.reload();
   }
   lastKa = ct;
+  keepaliveLost = false;
+  updateTimeInState();
   let target = document.getElementById("status-bar");
   target.classList.remove("amber", "green", "grey", "noUpdate", "red");
-  target.classList.add("green");
+  target.classList.add(target.dataset.health || "green");
   target.getElementsByClassName("message")[0].innerHTML = "";
   if (typeof ka !== "undefined") {
     clearTimeout(ka);
   }
   ka = setTimeout(function () {
+    keepaliveLost = true;
     target.classList.remove("amber", "green", "grey", "noUpdate", "red");
     target.classList.add("noUpdate");
     target.getElementsByClassName("message")[0].innerHTML =
@@ -180,6 +492,9 @@ function changeAlertLevel(target, status, message) {
   if (["amber", "green", "grey", "noUpdate", "red"].indexOf(status) === -1) {
     status = "grey";
   }
+  if (!target.classList.contains(status)) {
+    target.classList.remove("acknowledged");
+  }
   target.classList.remove("amber", "green", "grey", "noUpdate", "red");
   target.classList.add(status);
   target.getElementsByClassName("message")[0].innerHTML = message;
@@ -188,7 +503,7 @@ function changeAlertLevel(target, status, message) {
   if (typeof (pMessages[0] != "undefined") && pMessages[0] != null) {
     pMessages[0].insertAdjacentHTML(
       "afterbegin",
-      "<li>" +
+      `<li data-status="${status}">` +
         myTime() +
         ": " +
         status.toUpperCase() +
@@ -196,13 +511,18 @@ function changeAlertLevel(target, status, message) {
         message +
         ")</li>",
     );
+    filterMessages();
   }
 }
 
 // Make big box to fit as many biggest boxes as will fit the current window.
+// In kiosk mode the dashboard is scaled so every box fits on the screen.
 function rightSizeBigBox(pageType) {
   let widthBox;
-  if (pageType === "dashboard") {
+  if (pageType === "dashboard" && isKiosk()) {
+    scaleToFit();
+    return;
+  } else if (pageType === "dashboard") {
     let availableWidth = Math.floor((window.innerWidth - 30) / 512) * 512;
     widthBox = availableWidth >= 512 ? availableWidth : 512;
   } else {
@@ -214,3 +534,360 @@ function rightSizeBigBox(pageType) {
     fullWidthBoxes[i].style.width = widthBox - 2 + "px";
   }
 }
+
+// Kiosk mode, for wall displays.
+function isKiosk() {
+  return document.body.classList.contains("kiosk");
+}
+
+// Find the largest scale the dashboard can be shown at without scrolling.
+function scaleToFit() {
+  let bigBox = document.getElementById("big-box");
+  let fullWidthBoxes = document.getElementsByClassName("fullwidth");
+  let layout = function (scale) {
+    let width = Math.floor(window.innerWidth / scale);
+    bigBox.style.width = width + "px";
+    for (let i = 0; i < fullWidthBoxes.length; i++) {
+      fullWidthBoxes[i].style.width = width - 2 + "px";
+    }
+    return bigBox.scrollHeight * scale <= window.innerHeight;
+  };
+
+  let low = 0.05;
+  let high = 4;
+  for (let i = 0; i < 20; i++) {
+    let mid = (low + high) / 2;
+    if (layout(mid)) {
+      low = mid;
+    } else {
+      high = mid;
+    }
+  }
+  layout(low);
+  bigBox.style.transformOrigin = "top left";
+  bigBox.style.transform = `scale(${low})`;
+}
+
+// Rotate between the dashboards in ?views= (comma separated slugs) every
+// ?rotate= seconds, 30 by default.
+function startKiosk() {
+  if (!isKiosk()) {
+    return;
+  }
+
+  let params = new URLSearchParams(window.location.search);
+  let views = (params.get("views") || "").split(",").filter((v) => v !== "");
+  if (views.length === 0) {
+    return;
+  }
+  let seconds = parseInt(params.get("rotate"), 10) || 30;
+  let next = (views.indexOf(dashboardSlug()) + 1) % views.length;
+
+  setTimeout(function () {
+    window.location.href =
+      `/d/${encodeURIComponent(views[next])}?` + params.toString();
+  }, seconds * 1000);
+}
+
+// Flash the whole screen when a box on it goes red.
+function flashRed(event) {
+  if (
+    !isKiosk() ||
+    event.quiet ||
+    event.status !== "red" ||
+    event.previousStatus === "red" ||
+    document.getElementById(event.id) === null
+  ) {
+    return;
+  }
+
+  let flash = document.getElementById("flash");
+  if (flash === null) {
+    return;
+  }
+  flash.classList.remove("flashing");
+  // Restart the animation if it is already running.
+  void flash.offsetWidth;
+  flash.classList.add("flashing");
+}
+
+// Desktop notifications and sounds when a box goes red or stops updating.
+// They are opt-in and the settings are kept in this browser.
+const alertStatuses = ["red", "noUpdate"];
+
+function alertSettings() {
+  return {
+    notify: localStorage.getItem("alive.alerts.notify") === "true",
+    sound: localStorage.getItem("alive.alerts.sound") === "true",
+    soundURL: localStorage.getItem("alive.alerts.soundURL") || "",
+  };
+}
+
+// Show the saved settings in the status bar.
+function loadAlertSettings() {
+  let notify = document.getElementById("alert-notify");
+  if (notify === null) {
+    return;
+  }
+  let settings = alertSettings();
+  notify.checked = settings.notify;
+  document.getElementById("alert-sound").checked = settings.sound;
+  document.getElementById("alert-sound-url").value = settings.soundURL;
+}
+
+document.addEventListener("DOMContentLoaded", loadAlertSettings);
+
+function saveAlertSettings() {
+  let notify = document.getElementById("alert-notify");
+  if (notify.checked) {
+    if (!("Notification" in window)) {
+      alert("This browser does not support desktop notifications.");
+      notify.checked = false;
+    } else if (Notification.permission !== "granted") {
+      Notification.requestPermission().then((permission) => {
+        if (permission !== "granted") {
+          notify.checked = false;
+          localStorage.setItem("alive.alerts.notify", false);
+        }
+      });
+    }
+  }
+
+  localStorage.setItem("alive.alerts.notify", notify.checked);
+  localStorage.setItem(
+    "alive.alerts.sound",
+    document.getElementById("alert-sound").checked,
+  );
+  localStorage.setItem(
+    "alive.alerts.soundURL",
+    document.getElementById("alert-sound-url").value.trim(),
+  );
+}
+
+// Notify when a box on this page goes into red or noUpdate, unless the
+// server has marked it as quiet.
+function raiseAlert(event) {
+  if (
+    event.quiet ||
+    alertStatuses.indexOf(event.status) === -1 ||
+    alertStatuses.indexOf(event.previousStatus) !== -1
+  ) {
+    return;
+  }
+  let target = document.getElementById(event.id);
+  if (target === null) {
+    return;
+  }
+
+  let settings = alertSettings();
+  if (
+    settings.notify &&
+    "Notification" in window &&
+    Notification.permission === "granted"
+  ) {
+    let title = target.querySelector(".title, h2");
+    let name = title === null ? event.id : title.textContent;
+    let notification = new Notification(`${name} is ${event.status}`, {
+      body: event.lastMessage || "",
+      tag: event.id,
+    });
+    notification.onclick = function () {
+      window.focus();
+      boxClick(event.id);
+    };
+  }
+
+  if (settings.sound) {
+    playAlertSound(settings.soundURL);
+  }
+}
+
+// Play the configured sound, or a short beep if there isn't one. Browsers
+// only allow this once the page has been interacted with.
+function playAlertSound(url) {
+  if (url !== "") {
+    new Audio(url).play().catch(() => {});
+    return;
+  }
+
+  let AudioContext = window.AudioContext || window.webkitAudioContext;
+  if (AudioContext === undefined) {
+    return;
+  }
+  let context = new AudioContext();
+  let oscillator = context.createOscillator();
+  oscillator.frequency.value = 880;
+  oscillator.connect(context.destination);
+  oscillator.start();
+  oscillator.stop(context.currentTime + 0.3);
+  oscillator.onended = function () {
+    context.close();
+  };
+}
+
+// Status bar summary, the number of boxes in each status and the worst status
+// of any box, kept up to date as boxes change.
+function updateSummary() {
+  let target = document.getElementById("status-bar");
+  if (target === null || target.dataset.health === undefined) {
+    // Not a page showing boxes.
+    return;
+  }
+
+  // The server lists the counts worst first.
+  let spans = target.getElementsByClassName("count");
+  let statusOrder = Array.from(spans, (span) => span.dataset.status);
+
+  let counts = {};
+  let tiles = document.getElementsByClassName("tile");
+  for (let i = 0; i < tiles.length; i++) {
+    let status = statusOrder.find((s) => tiles[i].classList.contains(s));
+    if (status !== undefined) {
+      counts[status] = (counts[status] || 0) + 1;
+    }
+  }
+
+  let worst = statusOrder.find((s) => counts[s] > 0) || "green";
+  for (let i = 0; i < spans.length; i++) {
+    let count = counts[spans[i].dataset.status] || 0;
+    spans[i].textContent = `${count} ${spans[i].dataset.status}`;
+    spans[i].classList.toggle("zero", count === 0);
+  }
+
+  target.dataset.health = worst;
+  // The status bar shows noUpdate while keepalives are missing.
+  if (!keepaliveLost) {
+    target.classList.remove("amber", "green", "grey", "noUpdate", "red");
+    target.classList.add(worst);
+  }
+}
+
+// Only show boxes with the status clicked, clicking it again shows them all.
+function filterStatus(status) {
+  let bigBox = document.getElementById("big-box");
+  if (bigBox.dataset.filter === status) {
+    delete bigBox.dataset.filter;
+  } else {
+    bigBox.dataset.filter = status;
+  }
+  document.body.onresize();
+}
+
+// Box info page, filter the previous messages by text and status. The filter
+// is kept when the page is resynced.
+let messageFilter = { text: "", status: "" };
+
+function filterMessages() {
+  let text = document.getElementById("message-filter-text");
+  let status = document.getElementById("message-filter-status");
+  if (text === null || status === null) {
+    return;
+  }
+  if (document.activeElement === text || document.activeElement === status) {
+    messageFilter = { text: text.value, status: status.value };
+  } else {
+    text.value = messageFilter.text;
+    status.value = messageFilter.status;
+  }
+
+  let search = messageFilter.text.toLowerCase();
+  let items = document.querySelectorAll(".previousMessages li");
+  for (let i = 0; i < items.length; i++) {
+    let show =
+      (messageFilter.status === "" ||
+        items[i].dataset.status === messageFilter.status) &&
+      items[i].textContent.toLowerCase().includes(search);
+    items[i].style.display = show ? "" : "none";
+  }
+}
+
+// Format a duration like Go does, e.g. 3h2m1s.
+function durationString(ms) {
+  let s = Math.max(0, Math.round(ms / 1000));
+  let h = Math.floor(s / 3600);
+  let m = Math.floor((s % 3600) / 60);
+  s = s % 60;
+  if (h > 0) {
+    return `${h}h${m}m${s}s`;
+  }
+  if (m > 0) {
+    return `${m}m${s}s`;
+  }
+  return `${s}s`;
+}
+
+function updateTimeInState() {
+  let cells = document.getElementsByClassName("timeInState");
+  for (let i = 0; i < cells.length; i++) {
+    if (cells[i].dataset.since) {
+      cells[i].textContent = durationString(
+        Date.now() - new Date(cells[i].dataset.since).getTime(),
+      );
+    }
+  }
+}
+
+// Metrics, add new values to the sparklines and the values shown on the info
+// page. Lines are drawn in a 100x20 box which is stretched to fit.
+const maxMetricPoints = 60;
+const sparklineSizes = ["medium", "dmedium", "large", "dlarge", "xlarge"];
+
+function updateMetrics(target, metrics) {
+  for (let i = 0; i < metrics.length; i++) {
+    let metric = metrics[i];
+    let svg = Array.from(target.getElementsByClassName("sparkline")).find(
+      (s) => s.dataset.metric === metric.name,
+    );
+    if (svg === undefined) {
+      if (target.classList.contains("info")) {
+        // A new metric, fetch the page again to get its row.
+        resync();
+        return;
+      }
+      if (
+        target.getElementsByClassName("sparkline").length > 0 ||
+        !sparklineSizes.some((size) => target.classList.contains(size))
+      ) {
+        continue;
+      }
+      target
+        .getElementsByClassName("message")[0]
+        .insertAdjacentHTML(
+          "afterend",
+          "<svg class='sparkline' viewBox='0 0 100 20' preserveAspectRatio='none'><title></title><polyline/></svg>",
+        );
+      svg = target.getElementsByClassName("sparkline")[0];
+      svg.dataset.metric = metric.name;
+      svg.dataset.values = "";
+    }
+
+    let values = svg.dataset.values === "" ? [] : svg.dataset.values.split(",");
+    values.push(String(metric.value));
+    values = values.slice(-maxMetricPoints);
+    svg.dataset.values = values.join(",");
+    drawSparkline(svg, values.map(Number));
+
+    let text = metric.unit ? `${metric.value} ${metric.unit}` : `${metric.value}`;
+    svg.getElementsByTagName("title")[0].textContent = `${metric.name}: ${text}`;
+    let cells = target.getElementsByClassName("metric-value");
+    for (let j = 0; j < cells.length; j++) {
+      if (cells[j].dataset.metric === metric.name) {
+        cells[j].textContent = text;
+      }
+    }
+  }
+}
+
+function drawSparkline(svg, values) {
+  if (values.length === 1) {
+    values = [values[0], values[0]];
+  }
+  let low = Math.min(...values);
+  let high = Math.max(...values);
+  let points = values.map(function (value, i) {
+    let x = (i * 100) / (values.length - 1);
+    let y = high > low ? 19 - ((value - low) / (high - low)) * 18 : 10;
+    return `${x.toFixed(2)},${y.toFixed(2)}`;
+  });
+  svg.getElementsByTagName("polyline")[0].setAttribute("points", points.join(" "));
+}
@@ -0,0 +1,38 @@
+package server
+
+import "github.com/baelish/alive/api"
+
+// statusOrder is the order statuses are counted in the status bar, worst
+// first.
+var statusOrder = api.StatusesByWorst()
+
+type statusCount struct {
+	Status api.Status
+	Count  int
+}
+
+// statusSummary is shown in the status bar of a dashboard, the number of boxes
+// in each status and the worst status of any box.
+type statusSummary struct {
+	Counts []statusCount
+	Worst  api.Status
+}
+
+func summariseStatuses(boxes []api.Box) *statusSummary {
+	counts := make(map[api.Status]int)
+	for _, box := range boxes {
+		counts[box.Status]++
+	}
+
+	summary := &statusSummary{Worst: api.Green}
+	worstFound := false
+	for _, status := range statusOrder {
+		summary.Counts = append(summary.Counts, statusCount{Status: status, Count: counts[status]})
+		if !worstFound && counts[status] > 0 {
+			summary.Worst = status
+			worstFound = true
+		}
+	}
+
+	return summary
+}
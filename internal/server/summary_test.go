@@ -0,0 +1,61 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/baelish/alive/api"
+)
+
+func TestSummariseStatuses(t *testing.T) {
+	tests := []struct {
+		name   string
+		boxes  []api.Box
+		counts string
+		worst  api.Status
+	}{
+		{
+			name:   "no boxes",
+			counts: "red:0 noUpdate:0 amber:0 grey:0 green:0",
+			worst:  api.Green,
+		},
+		{
+			name:   "all green",
+			boxes:  []api.Box{{Status: api.Green}, {Status: api.Green}},
+			counts: "red:0 noUpdate:0 amber:0 grey:0 green:2",
+			worst:  api.Green,
+		},
+		{
+			name:   "grey is worse than green",
+			boxes:  []api.Box{{Status: api.Green}, {Status: api.Grey}},
+			counts: "red:0 noUpdate:0 amber:0 grey:1 green:1",
+			worst:  api.Grey,
+		},
+		{
+			name:   "red is worst",
+			boxes:  []api.Box{{Status: api.Amber}, {Status: api.NoUpdate}, {Status: api.Red}, {Status: api.Green}},
+			counts: "red:1 noUpdate:1 amber:1 grey:0 green:1",
+			worst:  api.Red,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := summariseStatuses(tt.boxes)
+
+			var counts string
+			for i, c := range summary.Counts {
+				if i > 0 {
+					counts += " "
+				}
+				counts += fmt.Sprintf("%s:%d", c.Status, c.Count)
+			}
+			if counts != tt.counts {
+				t.Errorf("expected counts %q, got %q", tt.counts, counts)
+			}
+			if summary.Worst != tt.worst {
+				t.Errorf("expected worst %s, got %s", tt.worst, summary.Worst)
+			}
+		})
+	}
+}
@@ -0,0 +1,417 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baelish/alive/api"
+
+	"go.uber.org/zap"
+)
+
+// The largest syslog message read, RFC 5424 only requires 2048 bytes.
+const maxSyslogMessageSize = 64 * 1024
+
+var (
+	// How long a TCP syslog connection can go without sending before it is
+	// closed.
+	syslogIdleTimeout = 5 * time.Minute
+	// The most TCP syslog connections served at once, more are closed as
+	// they are accepted.
+	maxSyslogConnections = 256
+)
+
+// syslogMessage is the part of a syslog message rules are matched against.
+type syslogMessage struct {
+	Hostname string
+	AppName  string
+	Message  string
+}
+
+type compiledSyslogRule struct {
+	rule    api.SyslogRule
+	host    *regexp.Regexp
+	pattern *regexp.Regexp
+}
+
+// SyslogRuleStore provides thread-safe access to the syslog rules
+type SyslogRuleStore struct {
+	mu       sync.RWMutex
+	rules    []api.SyslogRule
+	compiled []compiledSyslogRule
+	save     func([]api.SyslogRule) error
+}
+
+// Global syslog rule store instance
+var syslogRuleStore = &SyslogRuleStore{save: saveSyslogRuleFile}
+
+var syslogRuleFile string
+
+// Rules returns a copy of the rules in order (thread-safe read)
+func (ss *SyslogRuleStore) Rules() []api.SyslogRule {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	return slices.Clone(ss.rules)
+}
+
+func compileSyslogRules(rules []api.SyslogRule) ([]compiledSyslogRule, error) {
+	compiled := make([]compiledSyslogRule, 0, len(rules))
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, compiledSyslogRule{
+			rule:    r,
+			host:    regexp.MustCompile(r.Host),
+			pattern: regexp.MustCompile(r.Pattern),
+		})
+	}
+
+	return compiled, nil
+}
+
+// SetRules replaces the rules without saving them, for loading them from the
+// data file (thread-safe write)
+func (ss *SyslogRuleStore) SetRules(rules []api.SyslogRule) error {
+	compiled, err := compileSyslogRules(rules)
+	if err != nil {
+		return err
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.rules = slices.Clone(rules)
+	ss.compiled = compiled
+	return nil
+}
+
+// Replace saves the rules and only then replaces the rules with them, so a
+// failed save leaves the rules as they were (thread-safe write)
+func (ss *SyslogRuleStore) Replace(rules []api.SyslogRule) error {
+	compiled, err := compileSyslogRules(rules)
+	if err != nil {
+		return err
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.save != nil {
+		if err := ss.save(rules); err != nil {
+			return err
+		}
+	}
+	ss.rules = slices.Clone(rules)
+	ss.compiled = compiled
+	return nil
+}
+
+// match returns the event for the first rule the message matches (thread-safe
+// read)
+func (ss *SyslogRuleStore) match(msg syslogMessage) (api.Event, bool) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	for _, c := range ss.compiled {
+		if !c.host.MatchString(msg.Hostname) {
+			continue
+		}
+		m := c.pattern.FindStringSubmatch(msg.Message)
+		if m == nil {
+			continue
+		}
+
+		vars := map[string]string{
+			"host":    msg.Hostname,
+			"app":     msg.AppName,
+			"message": msg.Message,
+		}
+		for i, name := range c.pattern.SubexpNames() {
+			if i > 0 {
+				vars[strconv.Itoa(i)] = m[i]
+			}
+			if name != "" {
+				vars[name] = m[i]
+			}
+		}
+		expand := func(s string) string {
+			return os.Expand(s, func(name string) string { return vars[name] })
+		}
+
+		event := api.Event{ID: expand(c.rule.ID), Status: *c.rule.Status, Message: msg.Message}
+		if c.rule.Message != "" {
+			event.Message = expand(c.rule.Message)
+		}
+		return event, true
+	}
+
+	return api.Event{}, false
+}
+
+func getSyslogRulesFromDataFile() {
+	syslogRuleFile = filepath.Clean(options.DataPath + "/syslog-rules.json")
+
+	var loaded []api.SyslogRule
+	if err := loadJSONFile(syslogRuleFile, &loaded); err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	if err := syslogRuleStore.SetRules(loaded); err != nil {
+		logger.Fatal(err.Error())
+	}
+}
+
+func saveSyslogRuleFile(rules []api.SyslogRule) error {
+	if syslogRuleFile == "" {
+		return nil
+	}
+
+	return saveJSONFile(syslogRuleFile, rules)
+}
+
+var (
+	// An RFC 3164 tag, the app name with an optional process ID.
+	syslogTagRe = regexp.MustCompile(`^([^\s:\[\]]{1,48})(?:\[[^\]]*\])?: ?`)
+	// An RFC 5424 structured data element.
+	syslogSDRe = regexp.MustCompile(`^\[(?:[^\]\\]|\\.)*\]`)
+)
+
+// parseSyslog parses an RFC 5424 or RFC 3164 message. Devices are lax about
+// the format, anything which can't be parsed is left in the message.
+func parseSyslog(line string) syslogMessage {
+	var msg syslogMessage
+	rest := strings.TrimRight(line, "\r\n\x00")
+
+	if strings.HasPrefix(rest, "<") {
+		if end := strings.IndexByte(rest, '>'); end > 1 && end <= 4 {
+			if _, err := strconv.Atoi(rest[1:end]); err == nil {
+				rest = rest[end+1:]
+			}
+		}
+	}
+
+	if strings.HasPrefix(rest, "1 ") {
+		// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+		if fields := strings.SplitN(rest[2:], " ", 6); len(fields) == 6 {
+			nilValue := func(s string) string {
+				if s == "-" {
+					return ""
+				}
+				return s
+			}
+			msg.Hostname = nilValue(fields[1])
+			msg.AppName = nilValue(fields[2])
+
+			rest = fields[5]
+			if strings.HasPrefix(rest, "-") {
+				rest = rest[1:]
+			} else {
+				for {
+					sd := syslogSDRe.FindString(rest)
+					if sd == "" {
+						break
+					}
+					rest = rest[len(sd):]
+				}
+			}
+			msg.Message = strings.TrimPrefix(strings.TrimPrefix(rest, " "), "\ufeff")
+			return msg
+		}
+	}
+
+	// Mmm dd hh:mm:ss HOSTNAME TAG: MSG
+	if len(rest) > len(time.Stamp) && rest[len(time.Stamp)] == ' ' {
+		if _, err := time.Parse(time.Stamp, rest[:len(time.Stamp)]); err == nil {
+			rest = rest[len(time.Stamp)+1:]
+			if host, after, ok := strings.Cut(rest, " "); ok && !strings.HasSuffix(host, ":") {
+				msg.Hostname = host
+				rest = after
+			}
+		}
+	}
+	if m := syslogTagRe.FindStringSubmatch(rest); m != nil {
+		msg.AppName = m[1]
+		rest = rest[len(m[0]):]
+	}
+	msg.Message = rest
+
+	return msg
+}
+
+// handleSyslog updates a box from a syslog message if it matches a rule. from
+// is the sender's address, used when the message has no hostname.
+func handleSyslog(line string, from string) {
+	msg := parseSyslog(line)
+	if msg.Hostname == "" {
+		msg.Hostname = from
+	}
+
+	event, ok := syslogRuleStore.match(msg)
+	if !ok {
+		syslogMessages.WithLabelValues("unmatched").Inc()
+		return
+	}
+	syslogMessages.WithLabelValues("matched").Inc()
+
+	if err := update(event); err != nil {
+		logger.Debug("syslog message not applied", zap.String("id", event.ID), zap.Error(err))
+	}
+}
+
+func remoteHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// runSyslog starts the syslog listeners which have been asked for.
+func runSyslog(ctx context.Context) {
+	if options.SyslogUDP != "" {
+		pc, err := net.ListenPacket("udp", options.SyslogUDP)
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		logger.Info("listening for syslog over udp", zap.String("address", options.SyslogUDP))
+		go serveSyslogUDP(ctx, pc)
+	}
+
+	if options.SyslogTCP != "" {
+		l, err := net.Listen("tcp", options.SyslogTCP)
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		logger.Info("listening for syslog over tcp", zap.String("address", options.SyslogTCP))
+		go serveSyslogTCP(ctx, l)
+	}
+}
+
+// serveSyslogUDP handles syslog messages, one per datagram.
+func serveSyslogUDP(ctx context.Context, pc net.PacketConn) {
+	stop := context.AfterFunc(ctx, func() { pc.Close() })
+	defer stop()
+
+	buf := make([]byte, maxSyslogMessageSize)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logger.Warn("syslog read failed", zap.Error(err))
+			continue
+		}
+		handleSyslog(string(buf[:n]), remoteHost(addr))
+	}
+}
+
+// idleReader sets a deadline before each read of a connection, so one which
+// stops sending is closed.
+type idleReader struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (r idleReader) Read(p []byte) (int, error) {
+	if err := r.conn.SetReadDeadline(time.Now().Add(r.timeout)); err != nil {
+		return 0, err
+	}
+	return r.conn.Read(p)
+}
+
+// serveSyslogTCP handles syslog connections, with messages framed by new
+// lines or by octet counting (RFC 6587).
+func serveSyslogTCP(ctx context.Context, l net.Listener) {
+	stop := context.AfterFunc(ctx, func() { l.Close() })
+	defer stop()
+
+	connections := make(chan struct{}, maxSyslogConnections)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logger.Warn("syslog accept failed", zap.Error(err))
+			continue
+		}
+
+		select {
+		case connections <- struct{}{}:
+		default:
+			logger.Debug("too many syslog connections", zap.String("from", remoteHost(conn.RemoteAddr())))
+			conn.Close()
+			continue
+		}
+
+		go func() {
+			defer func() { <-connections }()
+			defer conn.Close()
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+
+			from := remoteHost(conn.RemoteAddr())
+			scanner := newSyslogScanner(idleReader{conn: conn, timeout: syslogIdleTimeout})
+			for scanner.Scan() {
+				if line := scanner.Text(); strings.TrimSpace(line) != "" {
+					handleSyslog(line, from)
+				}
+			}
+			if err := scanner.Err(); err != nil && ctx.Err() == nil {
+				logger.Debug("syslog connection closed", zap.String("from", from), zap.Error(err))
+			}
+		}()
+	}
+}
+
+// newSyslogScanner returns a scanner for syslog messages sent over TCP. Its
+// buffer has room for the largest message with its octet count, or with its
+// new line.
+func newSyslogScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	header := len(strconv.Itoa(maxSyslogMessageSize)) + 1
+	scanner.Buffer(make([]byte, 4096), maxSyslogMessageSize+header)
+	scanner.Split(splitSyslog)
+	return scanner
+}
+
+// splitSyslog is a bufio.SplitFunc for syslog over TCP. A message starting
+// with a length and a space is octet counted, otherwise it ends at a new line.
+func splitSyslog(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) > 0 && data[0] >= '1' && data[0] <= '9' {
+		sp := bytes.IndexByte(data, ' ')
+		waiting := (sp < 0 && len(data) < 10 && bytes.IndexByte(data, '\n') < 0) || sp == len(data)-1
+		if waiting && !atEOF {
+			return 0, nil, nil
+		}
+		if sp > 0 && sp+1 < len(data) && data[sp+1] == '<' {
+			// Lengths which can't be a message are left to frame by new
+			// line, this also stops huge ones overflowing.
+			if n, err := strconv.Atoi(string(data[:sp])); err == nil && n > 0 && n <= maxSyslogMessageSize {
+				if len(data)-(sp+1) >= n {
+					return sp + 1 + n, data[sp+1 : sp+1+n], nil
+				}
+				if !atEOF {
+					return 0, nil, nil
+				}
+			}
+		}
+	}
+
+	return bufio.ScanLines(data, atEOF)
+}
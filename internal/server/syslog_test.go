@@ -0,0 +1,373 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baelish/alive/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestParseSyslog(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		expect syslogMessage
+	}{
+		{
+			name:   "rfc 5424",
+			line:   "<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut=\"3\" eventSource=\"Application\"] \ufeffAn application event log entry\n",
+			expect: syslogMessage{Hostname: "mymachine.example.com", AppName: "evntslog", Message: "An application event log entry"},
+		},
+		{
+			name:   "rfc 5424 without structured data",
+			line:   "<34>1 2003-10-11T22:14:15.003Z - su - - - 'su root' failed",
+			expect: syslogMessage{AppName: "su", Message: "'su root' failed"},
+		},
+		{
+			name:   "rfc 5424 with escaped bracket",
+			line:   `<34>1 - host app - - [a@1 x="[\]"][b@1 y="2"] done`,
+			expect: syslogMessage{Hostname: "host", AppName: "app", Message: "done"},
+		},
+		{
+			name:   "rfc 3164",
+			line:   "<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8",
+			expect: syslogMessage{Hostname: "mymachine", AppName: "su", Message: "'su root' failed for lonvick on /dev/pts/8"},
+		},
+		{
+			name:   "rfc 3164 without hostname",
+			line:   "<13>Feb  5 17:32:18 ups: on battery",
+			expect: syslogMessage{AppName: "ups", Message: "on battery"},
+		},
+		{
+			name:   "no header",
+			line:   "<13>link down on port 4",
+			expect: syslogMessage{Message: "link down on port 4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSyslog(tt.line); got != tt.expect {
+				t.Errorf("expected %+v, got %+v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestSplitSyslog(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		expect []string
+	}{
+		{
+			name:   "new lines",
+			stream: "<13>one\n<13>two\r\n<13>three",
+			expect: []string{"<13>one", "<13>two", "<13>three"},
+		},
+		{
+			name:   "octet counting",
+			stream: "8 <13>one\n7 <13>two9 <13>three",
+			expect: []string{"<13>one\n", "<13>two", "<13>three"},
+		},
+		{
+			name:   "largest message",
+			stream: fmt.Sprintf("%d <13>%s", maxSyslogMessageSize, strings.Repeat("x", maxSyslogMessageSize-4)),
+			expect: []string{"<13>" + strings.Repeat("x", maxSyslogMessageSize-4)},
+		},
+		{
+			name:   "largest line",
+			stream: "<13>" + strings.Repeat("x", maxSyslogMessageSize-4) + "\r\n<13>next",
+			expect: []string{"<13>" + strings.Repeat("x", maxSyslogMessageSize-4), "<13>next"},
+		},
+		{
+			name:   "octet count too big",
+			stream: "9223372036854775807 <13>hi\n<13>next",
+			expect: []string{"9223372036854775807 <13>hi", "<13>next"},
+		},
+		{
+			name:   "line starting with a number",
+			stream: "2024 was a good year\n<13>next\n",
+			expect: []string{"2024 was a good year", "<13>next"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := newSyslogScanner(strings.NewReader(tt.stream))
+			var got []string
+			for scanner.Scan() {
+				got = append(got, scanner.Text())
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected %q, got %q", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestSyslogRuleStore_Match(t *testing.T) {
+	store := &SyslogRuleStore{}
+	err := store.SetRules([]api.SyslogRule{
+		{Host: `^ups\d+$`, Pattern: `on battery`, ID: "ups-${host}", Status: ptr(api.Red)},
+		{Host: `^ups\d+$`, Pattern: `on line`, ID: "ups-${host}", Status: ptr(api.Green), Message: "power restored"},
+		{Pattern: `port (?P<port>\d+) (?:is )?down`, ID: "${host}-port-$port", Status: ptr(api.Amber), Message: "${app}: port $1 down"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		msg         syslogMessage
+		expect      api.Event
+		expectMatch bool
+	}{
+		{
+			name:        "host and pattern",
+			msg:         syslogMessage{Hostname: "ups1", Message: "on battery, 80% left"},
+			expect:      api.Event{ID: "ups-ups1", Status: api.Red, Message: "on battery, 80% left"},
+			expectMatch: true,
+		},
+		{
+			name:        "rule message",
+			msg:         syslogMessage{Hostname: "ups1", Message: "on line"},
+			expect:      api.Event{ID: "ups-ups1", Status: api.Green, Message: "power restored"},
+			expectMatch: true,
+		},
+		{
+			name: "host does not match",
+			msg:  syslogMessage{Hostname: "web1", Message: "on battery"},
+		},
+		{
+			name:        "groups",
+			msg:         syslogMessage{Hostname: "switch1", AppName: "lldp", Message: "port 4 is down"},
+			expect:      api.Event{ID: "switch1-port-4", Status: api.Amber, Message: "lldp: port 4 down"},
+			expectMatch: true,
+		},
+		{
+			name: "no rule matches",
+			msg:  syslogMessage{Hostname: "switch1", Message: "port 4 up"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := store.match(tt.msg)
+			if ok != tt.expectMatch {
+				t.Fatalf("expected match %v, got %v", tt.expectMatch, ok)
+			}
+			if ok && !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected %+v, got %+v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestSyslogRuleStore_SaveFirst(t *testing.T) {
+	saveErr := errors.New("disk full")
+	store := &SyslogRuleStore{save: func([]api.SyslogRule) error { return saveErr }}
+	if err := store.SetRules([]api.SyslogRule{{Pattern: "on battery", ID: "ups", Status: ptr(api.Red)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Replace([]api.SyslogRule{{Pattern: "on line", ID: "ups", Status: ptr(api.Green)}}); !errors.Is(err, saveErr) {
+		t.Errorf("expected the save error, got %v", err)
+	}
+	if rules := store.Rules(); len(rules) != 1 || rules[0].Pattern != "on battery" {
+		t.Errorf("expected the rules to be unchanged after a failed save, got %+v", rules)
+	}
+	if _, ok := store.match(syslogMessage{Message: "on battery"}); !ok {
+		t.Error("expected the old rules to still be matched")
+	}
+}
+
+func TestServeSyslog(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	originalRules := syslogRuleStore.Rules()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+		syslogRuleStore.SetRules(originalRules)
+	}()
+
+	resetBoxStore()
+	boxStore.Add(api.Box{ID: "udp", Status: api.Grey})
+	boxStore.Add(api.Box{ID: "tcp", Status: api.Grey})
+	if err := syslogRuleStore.SetRules([]api.SyslogRule{
+		{Pattern: `^(udp|tcp) failed$`, ID: "$1", Status: ptr(api.Red)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go serveSyslogUDP(ctx, pc)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go serveSyslogTCP(ctx, l)
+
+	udp, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	fmt.Fprint(udp, "<11>Oct 11 22:14:15 host1 app: udp failed")
+
+	tcp, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+	framed := "<11>1 - host1 app - - - tcp failed"
+	fmt.Fprintf(tcp, "<11>1 - host1 app - - - unmatched\n%d %s", len(framed), framed)
+
+	for _, id := range []string{"udp", "tcp"} {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			box, err := boxStore.GetByID(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if box.Status == api.Red && box.LastMessage == id+" failed" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected box %s to go red, got %s %q", id, box.Status, box.LastMessage)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestServeSyslogTCP_Limits(t *testing.T) {
+	originalIdleTimeout := syslogIdleTimeout
+	originalMaxConnections := maxSyslogConnections
+	defer func() {
+		syslogIdleTimeout = originalIdleTimeout
+		maxSyslogConnections = originalMaxConnections
+	}()
+
+	// closed reports whether the server closed the connection, rather than
+	// it still being open when the wait is over.
+	closed := func(conn net.Conn, wait time.Duration) bool {
+		conn.SetReadDeadline(time.Now().Add(wait))
+		_, err := conn.Read(make([]byte, 1))
+		var netErr net.Error
+		return !(errors.As(err, &netErr) && netErr.Timeout())
+	}
+
+	serve := func(t *testing.T) string {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go serveSyslogTCP(ctx, l)
+		return l.Addr().String()
+	}
+
+	dial := func(t *testing.T, addr string) net.Conn {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	t.Run("idle connections are closed", func(t *testing.T) {
+		syslogIdleTimeout = 50 * time.Millisecond
+		maxSyslogConnections = originalMaxConnections
+
+		conn := dial(t, serve(t))
+		if !closed(conn, 2*time.Second) {
+			t.Error("expected an idle connection to be closed")
+		}
+	})
+
+	t.Run("connections are limited", func(t *testing.T) {
+		syslogIdleTimeout = originalIdleTimeout
+		maxSyslogConnections = 1
+
+		addr := serve(t)
+		first := dial(t, addr)
+		second := dial(t, addr)
+		if !closed(second, 2*time.Second) {
+			t.Error("expected a connection over the limit to be closed")
+		}
+		if closed(first, 100*time.Millisecond) {
+			t.Error("expected the first connection to be kept open")
+		}
+	})
+}
+
+func TestApiPutSyslogRules(t *testing.T) {
+	originalRules := syslogRuleStore.Rules()
+	originalRuleFile := syslogRuleFile
+	originalDataPath := options.DataPath
+	defer func() {
+		syslogRuleStore.SetRules(originalRules)
+		syslogRuleFile = originalRuleFile
+		options.DataPath = originalDataPath
+	}()
+
+	options.DataPath = t.TempDir()
+	getSyslogRulesFromDataFile()
+
+	router := chi.NewRouter()
+	router.Get("/api/v1/syslog/rules", apiGetSyslogRules)
+	router.Put("/api/v1/syslog/rules", apiPutSyslogRules)
+
+	tests := []struct {
+		name       string
+		body       string
+		expectCode int
+	}{
+		{"valid", `[{"pattern": "on battery", "id": "ups", "status": "red"}]`, http.StatusOK},
+		{"invalid pattern", `[{"pattern": "(", "id": "ups", "status": "red"}]`, http.StatusBadRequest},
+		{"no id", `[{"pattern": "on battery", "status": "red"}]`, http.StatusBadRequest},
+		{"no status", `[{"pattern": "on battery", "id": "ups"}]`, http.StatusBadRequest},
+		{"invalid status", `[{"pattern": "on battery", "id": "ups", "status": "blue"}]`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/syslog/rules", strings.NewReader(tt.body)))
+			if rec.Code != tt.expectCode {
+				t.Errorf("expected %d, got %d: %s", tt.expectCode, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	// The valid rules are kept, saved and reloaded.
+	syslogRuleStore.SetRules(nil)
+	getSyslogRulesFromDataFile()
+	if rules := syslogRuleStore.Rules(); len(rules) != 1 || rules[0].ID != "ups" {
+		t.Errorf("expected rules to be reloaded from file, got %+v", rules)
+	}
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/baelish/alive/api"
+
+	"go.uber.org/zap"
+)
+
+// TemplateStore provides thread-safe access to box templates
+type TemplateStore = namedStore[api.Template]
+
+// Global template store instance
+var templateStore = newNamedStore(func(t api.Template) string { return t.Name }, saveTemplateFile)
+
+var templateFile string
+
+func getTemplatesFromDataFile() {
+	templateFile = filepath.Clean(options.DataPath + "/templates.json")
+
+	var loaded []api.Template
+	if err := loadJSONFile(templateFile, &loaded); err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	templateStore.Set(loaded)
+}
+
+func saveTemplateFile(templates []api.Template) error {
+	if templateFile == "" {
+		return nil
+	}
+
+	return saveJSONFile(templateFile, templates)
+}
+
+// walkStrings calls fn on every string, including map keys, in a value
+// decoded from JSON, replacing it with the result.
+func walkStrings(v any, fn func(string) (string, error)) (any, error) {
+	switch value := v.(type) {
+	case string:
+		return fn(value)
+	case []any:
+		for i := range value {
+			var err error
+			if value[i], err = walkStrings(value[i], fn); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]any:
+		result := make(map[string]any, len(value))
+		for k, item := range value {
+			key, err := fn(k)
+			if err != nil {
+				return nil, err
+			}
+			if result[key], err = walkStrings(item, fn); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+
+	return v, nil
+}
+
+// executeTemplate fills in the placeholders in the strings of a template's
+// box. With nil vars it only checks the placeholders can be parsed.
+func executeTemplate(t api.Template, vars map[string]string) (api.Box, error) {
+	var box api.Box
+
+	b, err := json.Marshal(t.Box)
+	if err != nil {
+		return box, err
+	}
+
+	var skeleton any
+	if err := json.Unmarshal(b, &skeleton); err != nil {
+		return box, err
+	}
+
+	rendered, err := walkStrings(skeleton, func(s string) (string, error) {
+		if !strings.Contains(s, "{{") {
+			return s, nil
+		}
+
+		tmpl, err := template.New(t.Name).Option("missingkey=error").Parse(s)
+		if err != nil || vars == nil {
+			return s, err
+		}
+
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, vars); err != nil {
+			return "", err
+		}
+
+		return sb.String(), nil
+	})
+	if err != nil {
+		return box, err
+	}
+
+	if b, err = json.Marshal(rendered); err != nil {
+		return box, err
+	}
+	if err := json.Unmarshal(b, &box); err != nil {
+		return box, err
+	}
+
+	return box, nil
+}
+
+// renderTemplate creates a box from a template, ready to be added.
+func renderTemplate(t api.Template, instance api.TemplateInstance) (api.Box, error) {
+	vars := instance.Vars
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+
+	box, err := executeTemplate(t, vars)
+	if err != nil {
+		return box, err
+	}
+
+	if box.ID == "" {
+		box.ID = instance.ID
+	}
+	box.Template = t.Name
+	box.TemplateVars = vars
+
+	return box, validateBox(box)
+}
+
+// copyTemplateMetadata copies the parts of a box which come from its template,
+// leaving its state (status, messages, etc.) alone.
+func copyTemplateMetadata(dst *api.Box, src api.Box) {
+	dst.Name = src.Name
+	dst.DisplayName = src.DisplayName
+	dst.Description = src.Description
+	dst.Size = src.Size
+	dst.Labels = src.Labels
+	dst.Parent = src.Parent
+	dst.Quiet = src.Quiet
+	dst.Links = src.Links
+	dst.Runbook = src.Runbook
+	dst.Thresholds = src.Thresholds
+	dst.MaxTBU = src.MaxTBU
+	dst.ExpireAfter = src.ExpireAfter
+	dst.Schedule = src.Schedule
+	dst.Grace = src.Grace
+	dst.ExpectedDuration = src.ExpectedDuration
+	dst.Check = src.Check
+
+	// Keep any info added by updates.
+	if src.Info != nil {
+		info := make(map[string]string)
+		if dst.Info != nil {
+			maps.Copy(info, *dst.Info)
+		}
+		maps.Copy(info, *src.Info)
+		dst.Info = &info
+	}
+}
+
+// propagateTemplate updates the metadata of the boxes created from a template
+// after it has changed, returning how many boxes were updated.
+func propagateTemplate(t api.Template) (updated int, err error) {
+	var errs []error
+	for _, box := range boxStore.GetAll() {
+		if box.Template != t.Name {
+			continue
+		}
+
+		rendered, err := renderTemplate(t, api.TemplateInstance{ID: box.ID, Vars: box.TemplateVars})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", box.ID, err))
+			continue
+		}
+
+		if err := boxStore.Update(box.ID, func(b *api.Box) { copyTemplateMetadata(b, rendered) }); err == nil {
+			updated++
+		}
+	}
+
+	if updated > 0 {
+		// Sizes and names may have changed.
+		boxStore.mu.Lock()
+		boxStore.sortUnsafe()
+		boxStore.mu.Unlock()
+
+		logger.Info("updated boxes from template", zap.String("template", t.Name), zap.Int("boxes", updated))
+		events.messages <- resyncMessage
+	}
+
+	return updated, errors.Join(errs...)
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baelish/alive/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func testTemplate() api.Template {
+	return api.Template{
+		Name: "web",
+		Box: api.Box{
+			ID:     "web-{{.host}}",
+			Name:   "{{.host}}",
+			Size:   api.Medium,
+			MaxTBU: ptr(api.Duration(5 * time.Minute)),
+			Links:  []api.Links{{Name: "Grafana", URL: "https://grafana/d/host?var-host={{.host}}"}},
+			Info:   &map[string]string{"host": "{{.host}}.example.com"},
+			Labels: map[string]string{"role": "web", "dc": "{{.dc}}"},
+		},
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    api.Template
+		instance    api.TemplateInstance
+		expect      api.Box
+		expectError bool
+	}{
+		{
+			name:     "all placeholders",
+			template: testTemplate(),
+			instance: api.TemplateInstance{Vars: map[string]string{"host": "web1", "dc": "lon"}},
+			expect: api.Box{
+				ID:           "web-web1",
+				Name:         "web1",
+				Size:         api.Medium,
+				MaxTBU:       ptr(api.Duration(5 * time.Minute)),
+				Links:        []api.Links{{Name: "Grafana", URL: "https://grafana/d/host?var-host=web1"}},
+				Info:         &map[string]string{"host": "web1.example.com"},
+				Labels:       map[string]string{"role": "web", "dc": "lon"},
+				Template:     "web",
+				TemplateVars: map[string]string{"host": "web1", "dc": "lon"},
+			},
+		},
+		{
+			name:     "id from instance",
+			template: api.Template{Name: "db", Box: api.Box{Name: "{{.host}} \"db\""}},
+			instance: api.TemplateInstance{ID: "db1", Vars: map[string]string{"host": "db1"}},
+			expect: api.Box{
+				ID:           "db1",
+				Name:         `db1 "db"`,
+				Template:     "db",
+				TemplateVars: map[string]string{"host": "db1"},
+			},
+		},
+		{
+			name:        "missing variable",
+			template:    testTemplate(),
+			instance:    api.TemplateInstance{Vars: map[string]string{"host": "web1"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box, err := renderTemplate(tt.template, tt.instance)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if tt.expectError {
+				return
+			}
+
+			if !reflect.DeepEqual(box, tt.expect) {
+				t.Errorf("expected %+v, got %+v", tt.expect, box)
+			}
+		})
+	}
+}
+
+func TestTemplateAPI(t *testing.T) {
+	originalBoxes := boxStore.GetAll()
+	originalTemplates := templateStore.GetAll()
+	originalTemplateFile := templateFile
+	originalDataPath := options.DataPath
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+		templateStore.Set(originalTemplates)
+		templateFile = originalTemplateFile
+		options.DataPath = originalDataPath
+	}()
+
+	resetBoxStore()
+	options.DataPath = t.TempDir()
+	getTemplatesFromDataFile()
+
+	router := chi.NewRouter()
+	router.Post("/api/v1/templates", apiCreateTemplate)
+	router.Put("/api/v1/templates/{name}", apiReplaceTemplate)
+	router.Post("/api/v1/templates/{name}/instantiate", apiInstantiateTemplate)
+
+	do := func(method, path string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(string(b))))
+		return rec
+	}
+
+	tmpl := testTemplate()
+	if rec := do("POST", "/api/v1/templates", tmpl); rec.Code != http.StatusCreated {
+		t.Fatalf("create template: expected %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body)
+	}
+	if rec := do("POST", "/api/v1/templates", tmpl); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate template: expected %d, got %d", http.StatusConflict, rec.Code)
+	}
+	if rec := do("POST", "/api/v1/templates", api.Template{Name: "bad", Box: api.Box{Name: "{{.host"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid template: expected %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec := do("POST", "/api/v1/templates/web/instantiate", api.TemplateInstance{Vars: map[string]string{"host": "web1", "dc": "lon"}})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("instantiate: expected %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/api/v1/boxes/web-web1" {
+		t.Errorf("expected location of the new box, got %q", loc)
+	}
+
+	rec = do("POST", "/api/v1/templates/web/instantiate", []api.TemplateInstance{
+		{Vars: map[string]string{"host": "web2", "dc": "lon"}},
+		{Vars: map[string]string{"host": "web3", "dc": "ams"}},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("instantiate list: expected %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body)
+	}
+	if boxStore.Len() != 3 {
+		t.Fatalf("expected 3 boxes, got %d", boxStore.Len())
+	}
+
+	if rec := do("POST", "/api/v1/templates/web/instantiate", api.TemplateInstance{Vars: map[string]string{"host": "web4"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing variable: expected %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := do("POST", "/api/v1/templates/nope/instantiate", api.TemplateInstance{}); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown template: expected %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	// The name can be left to the path when replacing.
+	if rec := do("PUT", "/api/v1/templates/api", api.Template{Box: api.Box{Name: "api {{.host}}"}}); rec.Code != http.StatusCreated {
+		t.Errorf("replace without a name: expected %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body)
+	}
+	if got, ok := templateStore.Get("api"); !ok || got.Name != "api" {
+		t.Errorf("expected the template to be named from the path, got %+v", got)
+	}
+	if rec := do("PUT", "/api/v1/templates/api", api.Template{Name: "other", Box: api.Box{Name: "api {{.host}}"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("mismatched name: expected %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	// Instances keep their state when the template changes.
+	update(api.Event{ID: "web-web2", Status: api.Red, Message: "down"})
+
+	tmpl.Box.Size = api.Large
+	tmpl.Box.Description = "Web server {{.host}} in {{.dc}}"
+	if rec := do("PUT", "/api/v1/templates/web", tmpl); rec.Code != http.StatusOK {
+		t.Fatalf("replace template: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+	if box, _ := boxStore.GetByID("web-web2"); box.Size != api.Medium {
+		t.Errorf("expected instances to be left alone without propagate, got size %s", box.Size)
+	}
+
+	threshold, err := api.ParseThreshold("latency_ms > 500 => amber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.Box.Parent = "frontend"
+	tmpl.Box.Quiet = true
+	tmpl.Box.Runbook = "https://wiki.example.com/runbooks/{{.host}}"
+	tmpl.Box.Thresholds = []api.Threshold{threshold}
+	if rec := do("PUT", "/api/v1/templates/web?propagate=true", tmpl); rec.Code != http.StatusOK {
+		t.Fatalf("propagate template: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+	box, _ := boxStore.GetByID("web-web2")
+	if box.Size != api.Large || box.Description != "Web server web2 in lon" {
+		t.Errorf("expected template changes to propagate, got size %s description %q", box.Size, box.Description)
+	}
+	if box.Parent != "frontend" || !box.Quiet || box.Runbook != "https://wiki.example.com/runbooks/web2" || len(box.Thresholds) != 1 || box.Thresholds[0].String() != threshold.String() {
+		t.Errorf("expected parent, quiet, runbook and thresholds to propagate, got %q %v %q %v", box.Parent, box.Quiet, box.Runbook, box.Thresholds)
+	}
+	if box.Status != api.Red || box.LastMessage != "down" {
+		t.Errorf("expected state to be kept, got %s %q", box.Status, box.LastMessage)
+	}
+
+	// Templates are persisted.
+	templateStore.Set(nil)
+	getTemplatesFromDataFile()
+	if got, ok := templateStore.Get("web"); !ok || got.Box.Description != tmpl.Box.Description {
+		t.Errorf("expected template to be reloaded from file, got %+v", got)
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var themeNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// themeFile is the stylesheet for a theme, which is loaded after
+// standard.css. Besides the built-in themes any theme-<name>.css added to the
+// static path can be used.
+func themeFile(name string) string {
+	return filepath.Join(options.StaticPath, "theme-"+name+".css")
+}
+
+func themeExists(name string) bool {
+	if !themeNameRe.MatchString(name) {
+		return false
+	}
+
+	_, err := os.Stat(themeFile(name))
+	return err == nil
+}
+
+// pageTheme returns the theme to use for a page, ?theme= if given otherwise
+// the theme passed. Themes which don't exist are ignored.
+func pageTheme(r *http.Request, theme string) string {
+	if t := r.URL.Query().Get("theme"); t != "" {
+		theme = t
+	}
+
+	if theme == "" || !themeExists(theme) {
+		return ""
+	}
+
+	return theme
+}
+
+// availableThemes returns the names of the themes in the static path.
+func availableThemes() []string {
+	files, err := filepath.Glob(filepath.Join(options.StaticPath, "theme-*.css"))
+	if err != nil {
+		return nil
+	}
+
+	var themes []string
+	for _, f := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(f), "theme-"), ".css")
+		if themeNameRe.MatchString(name) {
+			themes = append(themes, name)
+		}
+	}
+	sort.Strings(themes)
+
+	return themes
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuiltInThemesEmbedded(t *testing.T) {
+	names := embeddedAssetNames()
+	for _, theme := range []string{"dark", "high-contrast", "deuteranopia"} {
+		if !slices.Contains(names, "/theme-"+theme+".css") {
+			t.Errorf("expected built-in theme %s to be embedded", theme)
+		}
+	}
+}
+
+func TestPageTheme(t *testing.T) {
+	originalStaticPath := options.StaticPath
+	defer func() { options.StaticPath = originalStaticPath }()
+
+	options.StaticPath = t.TempDir()
+	for _, name := range []string{"theme-dark.css", "theme-ours.css", "theme-bad name.css", "standard.css"} {
+		if err := os.WriteFile(filepath.Join(options.StaticPath, name), []byte("body {}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		url    string
+		theme  string
+		expect string
+	}{
+		{name: "none", url: "/", expect: ""},
+		{name: "dashboard theme", url: "/", theme: "dark", expect: "dark"},
+		{name: "query overrides dashboard", url: "/?theme=ours", theme: "dark", expect: "ours"},
+		{name: "custom theme", url: "/?theme=ours", expect: "ours"},
+		{name: "missing theme", url: "/?theme=pink", expect: ""},
+		{name: "path traversal", url: "/?theme=../standard", expect: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pageTheme(httptest.NewRequest("GET", tt.url, nil), tt.theme); got != tt.expect {
+				t.Errorf("expected %q, got %q", tt.expect, got)
+			}
+		})
+	}
+
+	if got := strings.Join(availableThemes(), ","); got != "dark,ours" {
+		t.Errorf("expected themes dark,ours, got %s", got)
+	}
+}
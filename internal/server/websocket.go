@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/coder/websocket"
+	"go.uber.org/zap"
+)
+
+// wsRequest is a message sent by a WebSocket client.
+//
+//	{"type": "subscribe", "ids": ["box1", "box2"]}
+//	{"type": "unsubscribe", "ids": ["box2"]}
+//	{"type": "ack", "id": "box1"}
+type wsRequest struct {
+	Type string   `json:"type"`
+	ID   string   `json:"id,omitempty"`
+	IDs  []string `json:"ids,omitempty"`
+}
+
+// eventFilter limits which box events are passed on to a client. Messages
+// which are not about a specific box (keepalives, resyncs) always match, as
+// does everything while no boxes are subscribed to.
+type eventFilter struct {
+	mu  sync.Mutex
+	ids map[string]bool
+}
+
+func (f *eventFilter) subscribe(ids []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.ids == nil {
+		f.ids = make(map[string]bool)
+	}
+	for _, id := range ids {
+		f.ids[id] = true
+	}
+}
+
+func (f *eventFilter) unsubscribe(ids []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, id := range ids {
+		delete(f.ids, id)
+	}
+}
+
+func (f *eventFilter) matches(data string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.ids) == 0 {
+		return true
+	}
+
+	var event struct {
+		ID  string `json:"id"`
+		Box *struct {
+			ID string `json:"id"`
+		} `json:"box"`
+	}
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return true
+	}
+
+	id := event.ID
+	if event.Box != nil {
+		id = event.Box.ID
+	}
+
+	return id == "" || f.ids[id]
+}
+
+// ServeWebSocket handles a WebSocket connection at "/ws". It carries the
+// same messages as "/events/", and the client can send wsRequests to filter
+// the boxes it hears about and to acknowledge boxes.
+func (b *Broker) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		logger.Warn("failed to accept websocket", zap.Error(err))
+		return
+	}
+	defer conn.CloseNow()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	c := newBrokerClient()
+	b.newClients <- c
+	defer func() {
+		b.defunctClients <- c
+		logger.Warn("websocket connection just closed")
+	}()
+
+	var filter eventFilter
+	go func() {
+		// Reading stops when the connection is closed, which also stops
+		// the writer below.
+		defer cancel()
+		for {
+			_, data, err := conn.Read(ctx)
+			if err != nil {
+				return
+			}
+			handleWsRequest(data, &filter)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case msg, open := <-c.messages:
+			if !open {
+				return
+			}
+
+			if !filter.matches(msg.data) {
+				continue
+			}
+
+			if err := conn.Write(ctx, websocket.MessageText, []byte(msg.data)); err != nil {
+				logger.Debug("failed to write to websocket", zap.Error(err))
+				return
+			}
+		}
+	}
+}
+
+func handleWsRequest(data []byte, filter *eventFilter) {
+	var req wsRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		logger.Warn("invalid websocket request", zap.Error(err))
+		return
+	}
+
+	switch req.Type {
+	case "subscribe":
+		filter.subscribe(req.IDs)
+	case "unsubscribe":
+		filter.unsubscribe(req.IDs)
+	case "ack":
+		if err := ackBox(req.ID); err != nil {
+			logger.Warn("failed to acknowledge box", zap.String("id", req.ID), zap.Error(err))
+		}
+	default:
+		logger.Warn("unknown websocket request", zap.String("type", req.Type))
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baelish/alive/api"
+	"github.com/coder/websocket"
+)
+
+func TestEventFilter(t *testing.T) {
+	var filter eventFilter
+
+	tests := []struct {
+		name   string
+		data   string
+		expect bool
+	}{
+		{name: "keepalive", data: `{"type": "keepalive"}`, expect: true},
+		{name: "subscribed update", data: `{"type": "updateBox", "id": "box1"}`, expect: true},
+		{name: "unsubscribed update", data: `{"type": "updateBox", "id": "box2"}`, expect: false},
+		{name: "subscribed create", data: `{"type": "createBox", "box": {"id": "box1"}}`, expect: true},
+		{name: "unsubscribed create", data: `{"type": "createBox", "box": {"id": "box2"}}`, expect: false},
+	}
+
+	if !filter.matches(`{"type": "updateBox", "id": "box2"}`) {
+		t.Error("expected an empty filter to match everything")
+	}
+
+	filter.subscribe([]string{"box1", "box3"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.matches(tt.data); got != tt.expect {
+				t.Errorf("expected %v, got %v", tt.expect, got)
+			}
+		})
+	}
+
+	filter.unsubscribe([]string{"box1", "box3"})
+	if !filter.matches(`{"type": "updateBox", "id": "box2"}`) {
+		t.Error("expected filter to match everything after unsubscribing")
+	}
+}
+
+func TestBroker_ServeWebSocket(t *testing.T) {
+	initTestLogger()
+
+	originalBoxes := boxStore.GetAll()
+	defer func() {
+		boxStore.mu.Lock()
+		boxStore.boxes = originalBoxes
+		boxStore.mu.Unlock()
+	}()
+	resetBoxStore()
+
+	broker := newBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker.Start(ctx)
+
+	server := httptest.NewServer(http.HandlerFunc(broker.ServeWebSocket))
+	defer server.Close()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.CloseNow()
+
+	read := func() string {
+		readCtx, readCancel := context.WithTimeout(ctx, time.Second)
+		defer readCancel()
+		_, data, err := conn.Read(readCtx)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		return string(data)
+	}
+
+	t.Run("receives broadcast messages", func(t *testing.T) {
+		broker.messages <- `{"type": "updateBox", "id": "box1"}`
+		if msg := read(); msg != `{"type": "updateBox", "id": "box1"}` {
+			t.Errorf("unexpected message %q", msg)
+		}
+	})
+
+	t.Run("only receives subscribed boxes", func(t *testing.T) {
+		if err := conn.Write(ctx, websocket.MessageText, []byte(`{"type": "subscribe", "ids": ["box2"]}`)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		broker.messages <- `{"type": "updateBox", "id": "box1"}`
+		broker.messages <- `{"type": "updateBox", "id": "box2"}`
+		if msg := read(); msg != `{"type": "updateBox", "id": "box2"}` {
+			t.Errorf("unexpected message %q", msg)
+		}
+	})
+
+	t.Run("acknowledges boxes", func(t *testing.T) {
+		if err := boxStore.Add(api.Box{ID: "ack-me", Name: "Ack me", Status: api.Red}); err != nil {
+			t.Fatalf("failed to add box: %v", err)
+		}
+
+		if err := conn.Write(ctx, websocket.MessageText, []byte(`{"type": "ack", "id": "ack-me"}`)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		box, err := boxStore.GetByID("ack-me")
+		if err != nil {
+			t.Fatalf("failed to get box: %v", err)
+		}
+		if !box.Acknowledged {
+			t.Error("expected box to be acknowledged")
+		}
+
+		// A change of status clears the acknowledgement
+		if err := update(api.Event{ID: "ack-me", Status: api.Green}); err != nil {
+			t.Fatalf("failed to update box: %v", err)
+		}
+		box, _ = boxStore.GetByID("ack-me")
+		if box.Acknowledged {
+			t.Error("expected acknowledgement to be cleared by a status change")
+		}
+	})
+
+	t.Run("unregisters on close", func(t *testing.T) {
+		conn.Close(websocket.StatusNormalClosure, "")
+		time.Sleep(20 * time.Millisecond)
+
+		if count := broker.ClientCount(); count != 0 {
+			t.Errorf("expected 0 clients, got %d", count)
+		}
+	})
+}